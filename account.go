@@ -0,0 +1,250 @@
+package main
+
+import (
+	"archive/zip"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Kurlgargyey/chirpy/internal/auth"
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// deleted accounts can be restored by logging in until the grace period is over
+const accountDeletionGracePeriod = 30 * 24 * time.Hour
+
+type deleteAccountRequestBody struct {
+	Password string `json:"password"`
+}
+
+type deleteAccountResponse struct {
+	PurgeAt time.Time `json:"purge_at"`
+}
+
+type exportedChirp struct {
+	ID        uuid.UUID  `json:"id"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	Body      string     `json:"body"`
+	ReplyTo   *uuid.UUID `json:"reply_to"`
+	RechirpOf *uuid.UUID `json:"rechirp_of"`
+	QuoteOf   *uuid.UUID `json:"quote_of"`
+}
+
+type exportedLike struct {
+	ChirpID   uuid.UUID `json:"chirp_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type accountExport struct {
+	ExportedAt time.Time         `json:"exported_at"`
+	Profile    userResponse      `json:"profile"`
+	Chirps     []exportedChirp   `json:"chirps"`
+	Likes      []exportedLike    `json:"likes"`
+	Sessions   []sessionResponse `json:"sessions"`
+}
+
+func (cfg *apiConfig) deleteAccountHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		var requestBody deleteAccountRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			writeError(w, fmt.Sprintf("error decoding json: %s", err), 400)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		if auth.CheckPasswordHash(requestBody.Password, user.HashedPassword) != nil {
+			writeError(w, "password is incorrect", 403)
+			return
+		}
+		if !user.DeletedAt.Valid {
+			err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+				if err := q.SoftDeleteUser(r.Context(), userID); err != nil {
+					return err
+				}
+				return q.RevokeAllRefreshTokens(r.Context(), userID)
+			})
+			if err != nil {
+				writeError(w, fmt.Sprintf("error deleting account: %s", err), 400)
+				return
+			}
+			user.DeletedAt = sql.NullTime{Time: time.Now().UTC(), Valid: true}
+		}
+		dat, _ := json.Marshal(deleteAccountResponse{PurgeAt: user.DeletedAt.Time.Add(accountDeletionGracePeriod)})
+		w.WriteHeader(202)
+		w.Write(dat)
+	})
+}
+
+// purgeDeletedAccounts removes accounts whose grace period has passed; their content goes with them via ON DELETE CASCADE.
+// Their chirps, and rechirps of them, are deleted explicitly first so that streams can be told.
+func (cfg *apiConfig) purgeDeletedAccounts(ctx context.Context) error {
+	cutoff := sql.NullTime{
+		Time:  time.Now().UTC().Add(-accountDeletionGracePeriod),
+		Valid: true,
+	}
+	var chirps []database.Chirp
+	var avatarKeys []string
+	err := cfg.withTx(ctx, func(q *database.Queries) error {
+		var err error
+		chirps, err = q.DeleteChirpsOfPurgedUsers(ctx, cutoff)
+		if err != nil {
+			return err
+		}
+		avatarKeys, err = q.PurgeDeletedUsers(ctx, cutoff)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	for _, chirp := range chirps {
+		cfg.publishChirpDeleted(chirp)
+	}
+	for _, key := range avatarKeys {
+		if key != "" {
+			cfg.avatars.Delete(ctx, key)
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) runAccountPurger(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := cfg.purgeDeletedAccounts(ctx); err != nil {
+			fmt.Println("error purging deleted accounts:", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (cfg *apiConfig) buildAccountExport(ctx context.Context, userID uuid.UUID) (accountExport, error) {
+	user, err := cfg.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return accountExport{}, err
+	}
+	chirps, err := cfg.db.GetUserChirpsAsc(ctx, userID)
+	if err != nil {
+		return accountExport{}, err
+	}
+	likes, err := cfg.db.GetAllUserLikes(ctx, userID)
+	if err != nil {
+		return accountExport{}, err
+	}
+	sessions, err := cfg.db.GetUserSessions(ctx, userID)
+	if err != nil {
+		return accountExport{}, err
+	}
+	export := accountExport{
+		ExportedAt: time.Now().UTC(),
+		Profile:    cfg.newUserResponse(user),
+		Chirps:     make([]exportedChirp, 0, len(chirps)),
+		Likes:      make([]exportedLike, 0, len(likes)),
+		Sessions:   make([]sessionResponse, 0, len(sessions)),
+	}
+	for _, chirp := range chirps {
+		exported := exportedChirp{
+			ID:        chirp.ID,
+			CreatedAt: chirp.CreatedAt,
+			UpdatedAt: chirp.UpdatedAt,
+			Body:      chirp.Body,
+		}
+		if chirp.ReplyTo.Valid {
+			exported.ReplyTo = &chirp.ReplyTo.UUID
+		}
+		if chirp.RechirpOf.Valid {
+			exported.RechirpOf = &chirp.RechirpOf.UUID
+		}
+		if chirp.QuoteOf.Valid {
+			exported.QuoteOf = &chirp.QuoteOf.UUID
+		}
+		export.Chirps = append(export.Chirps, exported)
+	}
+	for _, like := range likes {
+		export.Likes = append(export.Likes, exportedLike{ChirpID: like.ChirpID, CreatedAt: like.CreatedAt})
+	}
+	for _, session := range sessions {
+		export.Sessions = append(export.Sessions, sessionResponse{
+			ID:         session.ID,
+			CreatedAt:  session.CreatedAt,
+			LastUsedAt: session.LastUsedAt,
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IpAddress,
+		})
+	}
+	return export, nil
+}
+
+// exportAccountHandler serves a ZIP with one JSON file per section, or a single JSON document with ?format=json.
+func (cfg *apiConfig) exportAccountHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "zip"
+		}
+		if format != "zip" && format != "json" {
+			writeError(w, "format must be zip or json", 400)
+			return
+		}
+		export, err := cfg.buildAccountExport(r.Context(), userID)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error exporting account: %s", err), 400)
+			return
+		}
+		filename := fmt.Sprintf("chirpy-export-%s.%s", export.ExportedAt.Format("2006-01-02"), format)
+		w.Header().Add("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		if format == "json" {
+			w.Header().Add("Content-Type", "application/json")
+			dat, _ := json.MarshalIndent(export, "", "  ")
+			w.WriteHeader(200)
+			w.Write(dat)
+			return
+		}
+		w.Header().Add("Content-Type", "application/zip")
+		w.WriteHeader(200)
+		archive := zip.NewWriter(w)
+		sections := []struct {
+			name    string
+			content any
+		}{
+			{"profile.json", export.Profile},
+			{"chirps.json", export.Chirps},
+			{"likes.json", export.Likes},
+			{"sessions.json", export.Sessions},
+		}
+		for _, section := range sections {
+			file, err := archive.CreateHeader(&zip.FileHeader{Name: section.name, Method: zip.Deflate, Modified: export.ExportedAt})
+			if err != nil {
+				return
+			}
+			dat, _ := json.MarshalIndent(section.content, "", "  ")
+			file.Write(dat)
+		}
+		archive.Close()
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Kurlgargyey/chirpy/internal/auth"
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/Kurlgargyey/chirpy/internal/dbtest"
+	"github.com/google/uuid"
+)
+
+func TestDeletedAccountIsHidden(t *testing.T) {
+	db := dbtest.Open(t)
+	cfg := &apiConfig{db: database.New(db), conn: db, jwtKeys: auth.NewHMACKeyRing("test secret")}
+	ctx := context.Background()
+
+	user, err := cfg.db.CreateUser(ctx, database.CreateUserParams{Email: "leaving@example.com", HashedPassword: "unused"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %s", err)
+	}
+	chirp, err := cfg.db.CreateChirp(ctx, database.CreateChirpParams{Body: "goodbye", UserID: user.ID})
+	if err != nil {
+		t.Fatalf("CreateChirp failed: %s", err)
+	}
+	token, err := auth.MakeJWT(user.ID, cfg.jwtKeys)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %s", err)
+	}
+	if _, err := cfg.activeUserID(ctx, token); err != nil {
+		t.Fatalf("token rejected before deletion: %s", err)
+	}
+
+	if err := cfg.db.SoftDeleteUser(ctx, user.ID); err != nil {
+		t.Fatalf("SoftDeleteUser failed: %s", err)
+	}
+	if _, err := cfg.activeUserID(ctx, token); !errors.Is(err, errAccountDeleted) {
+		t.Fatalf("token of a deleted account: got error %v, want %v", err, errAccountDeleted)
+	}
+	chirps, err := cfg.db.GetChirpsPageDesc(ctx, database.GetChirpsPageDescParams{
+		AuthorID:  uuid.NullUUID{UUID: user.ID, Valid: true},
+		PageLimit: 10,
+	})
+	if err != nil {
+		t.Fatalf("GetChirpsPageDesc failed: %s", err)
+	}
+	if len(chirps) != 0 {
+		t.Fatalf("deleted account's chirps are listed: %v", chirps)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/chirps/"+chirp.ID.String(), nil)
+	req.SetPathValue("chirpID", chirp.ID.String())
+	rec := httptest.NewRecorder()
+	cfg.getChirpHandler().ServeHTTP(rec, req)
+	if rec.Code != 404 {
+		t.Fatalf("GET deleted account's chirp: got status %d, want 404", rec.Code)
+	}
+}
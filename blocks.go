@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/Kurlgargyey/chirpy/internal/pubsub"
+	"github.com/google/uuid"
+)
+
+// Live streams hide chirps from users the subscriber blocked or muted. A subscriber's streams
+// reload that set when a relationship_changed event for them is published; these events are
+// never sent to clients.
+const (
+	topicRelationships       = "relationships"
+	eventRelationshipChanged = "relationship_changed"
+)
+
+// isBlockedBy reports whether ownerID has blocked userID, in which case userID
+// may not reply to, like, rechirp or follow ownerID.
+func (cfg *apiConfig) isBlockedBy(ctx context.Context, ownerID, userID uuid.UUID) (bool, error) {
+	return cfg.db.IsBlocked(ctx, database.IsBlockedParams{
+		BlockerID: ownerID,
+		BlockedID: userID,
+	})
+}
+
+// hiddenAuthors returns the users whose chirps viewerID has blocked or muted.
+func (cfg *apiConfig) hiddenAuthors(ctx context.Context, viewerID uuid.NullUUID) (map[uuid.UUID]bool, error) {
+	hidden := map[uuid.UUID]bool{}
+	if !viewerID.Valid {
+		return hidden, nil
+	}
+	ids, err := cfg.db.GetHiddenUserIDs(ctx, viewerID.UUID)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		hidden[id] = true
+	}
+	return hidden, nil
+}
+
+func (cfg *apiConfig) publishRelationshipChanged(userID uuid.UUID) {
+	cfg.events.Publish(pubsub.Event{
+		Topic:  topicRelationships,
+		Type:   eventRelationshipChanged,
+		UserID: userID,
+	})
+}
+
+func isRelationshipChange(event pubsub.Event, viewerID uuid.NullUUID) bool {
+	return viewerID.Valid && event.Topic == topicRelationships && event.UserID == viewerID.UUID
+}
+
+// relationshipTarget authenticates the caller and resolves the {userID} they want to block or mute.
+// It writes the error response itself and returns ok=false on failure.
+func (cfg *apiConfig) relationshipTarget(w http.ResponseWriter, r *http.Request, checkExists bool) (uuid.UUID, uuid.UUID, bool) {
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		w.WriteHeader(401)
+		return uuid.Nil, uuid.Nil, false
+	}
+	targetID, err := uuid.Parse(r.PathValue("userID"))
+	if err != nil {
+		writeError(w, "invalid user id", 404)
+		return uuid.Nil, uuid.Nil, false
+	}
+	if targetID == userID {
+		writeError(w, "users cannot block or mute themselves", 400)
+		return uuid.Nil, uuid.Nil, false
+	}
+	if checkExists {
+		if _, err := cfg.db.GetUserByID(r.Context(), targetID); err != nil {
+			writeError(w, "user not found", 404)
+			return uuid.Nil, uuid.Nil, false
+		}
+	}
+	return userID, targetID, true
+}
+
+// blockUserHandler also removes any follow relationship in either direction.
+func (cfg *apiConfig) blockUserHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, targetID, ok := cfg.relationshipTarget(w, r, true)
+		if !ok {
+			return
+		}
+		err := cfg.withTx(r.Context(), func(q *database.Queries) error {
+			err := q.BlockUser(r.Context(), database.BlockUserParams{BlockerID: userID, BlockedID: targetID})
+			if err != nil {
+				return err
+			}
+			err = q.UnfollowUser(r.Context(), database.UnfollowUserParams{FollowerID: userID, FolloweeID: targetID})
+			if err != nil {
+				return err
+			}
+			return q.UnfollowUser(r.Context(), database.UnfollowUserParams{FollowerID: targetID, FolloweeID: userID})
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error blocking user: %s", err), 400)
+			return
+		}
+		cfg.publishRelationshipChanged(userID)
+		w.WriteHeader(204)
+	})
+}
+
+func (cfg *apiConfig) unblockUserHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, targetID, ok := cfg.relationshipTarget(w, r, false)
+		if !ok {
+			return
+		}
+		err := cfg.db.UnblockUser(r.Context(), database.UnblockUserParams{BlockerID: userID, BlockedID: targetID})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error unblocking user: %s", err), 400)
+			return
+		}
+		cfg.publishRelationshipChanged(userID)
+		w.WriteHeader(204)
+	})
+}
+
+func (cfg *apiConfig) muteUserHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, targetID, ok := cfg.relationshipTarget(w, r, true)
+		if !ok {
+			return
+		}
+		err := cfg.db.MuteUser(r.Context(), database.MuteUserParams{MuterID: userID, MutedID: targetID})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error muting user: %s", err), 400)
+			return
+		}
+		cfg.publishRelationshipChanged(userID)
+		w.WriteHeader(204)
+	})
+}
+
+func (cfg *apiConfig) unmuteUserHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, targetID, ok := cfg.relationshipTarget(w, r, false)
+		if !ok {
+			return
+		}
+		err := cfg.db.UnmuteUser(r.Context(), database.UnmuteUserParams{MuterID: userID, MutedID: targetID})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error unmuting user: %s", err), 400)
+			return
+		}
+		cfg.publishRelationshipChanged(userID)
+		w.WriteHeader(204)
+	})
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,15 +9,34 @@ import (
 
 	"github.com/Kurlgargyey/chirpy/internal/auth"
 	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/Kurlgargyey/chirpy/internal/validation"
 	"github.com/google/uuid"
 )
 
 type chirpRequestBody struct {
-	Body   string `json:"body"`
-	UserID string `json:"user_id"`
+	Body    *string    `json:"body"`
+	UserID  string     `json:"user_id"`
+	ReplyTo *uuid.UUID `json:"reply_to"`
 }
 
 type chirpResponse struct {
+	ID           uuid.UUID         `json:"id"`
+	CreatedAt    time.Time         `json:"created_at"`
+	UpdatedAt    time.Time         `json:"updated_at"`
+	Body         string            `json:"body"`
+	UserID       uuid.UUID         `json:"user_id"`
+	ReplyTo      *uuid.UUID        `json:"reply_to"`
+	ReplyCount   int64             `json:"reply_count"`
+	LikeCount    int64             `json:"like_count"`
+	LikedByMe    *bool             `json:"liked_by_me,omitempty"`
+	RechirpCount int64             `json:"rechirp_count"`
+	Mentions     []mentionResponse `json:"mentions"`
+	// RechirpedChirp and QuotedChirp hold an embeddedChirpResponse, or a chirpTombstone once the original is gone or hidden from the viewer.
+	RechirpedChirp any `json:"rechirped_chirp,omitempty"`
+	QuotedChirp    any `json:"quoted_chirp,omitempty"`
+}
+
+type embeddedChirpResponse struct {
 	ID        uuid.UUID `json:"id"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
@@ -24,6 +44,201 @@ type chirpResponse struct {
 	UserID    uuid.UUID `json:"user_id"`
 }
 
+type chirpTombstone struct {
+	Deleted bool `json:"deleted"`
+}
+
+type chirpPageResponse struct {
+	Chirps     []chirpResponse `json:"chirps"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+}
+
+type chirpRevisionResponse struct {
+	Body       string    `json:"body"`
+	CreatedAt  time.Time `json:"created_at"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+type chirpHistoryResponse struct {
+	Chirp     chirpResponse           `json:"chirp"`
+	Revisions []chirpRevisionResponse `json:"revisions"`
+}
+
+type chirpThreadResponse struct {
+	Ancestors   []chirpResponse `json:"ancestors"`
+	Chirp       chirpResponse   `json:"chirp"`
+	Descendants []chirpResponse `json:"descendants"`
+}
+
+const maxThreadDescendants = 500
+
+// chirpResponses converts chirps into their API representation, filling in the aggregate counts in bulk.
+// Per-viewer fields such as liked_by_me are only set when viewer is valid.
+func (cfg *apiConfig) chirpResponses(ctx context.Context, viewer uuid.NullUUID, chirps []database.Chirp) ([]chirpResponse, error) {
+	responses := make([]chirpResponse, 0, len(chirps))
+	if len(chirps) == 0 {
+		return responses, nil
+	}
+	ids := make([]uuid.UUID, 0, len(chirps))
+	for _, chirp := range chirps {
+		ids = append(ids, chirp.ID)
+	}
+	replyCounts, err := cfg.db.GetReplyCounts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	replyCountByID := make(map[uuid.UUID]int64, len(replyCounts))
+	for _, row := range replyCounts {
+		replyCountByID[row.ReplyTo.UUID] = row.ReplyCount
+	}
+	likeCounts, err := cfg.db.GetLikeCounts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	likeCountByID := make(map[uuid.UUID]int64, len(likeCounts))
+	for _, row := range likeCounts {
+		likeCountByID[row.ChirpID] = row.LikeCount
+	}
+	rechirpCounts, err := cfg.db.GetRechirpCounts(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	rechirpCountByID := make(map[uuid.UUID]int64, len(rechirpCounts))
+	for _, row := range rechirpCounts {
+		rechirpCountByID[row.RechirpOf.UUID] = row.RechirpCount
+	}
+	embedded, err := cfg.embeddedChirps(ctx, viewer, chirps)
+	if err != nil {
+		return nil, err
+	}
+	mentions, err := cfg.db.GetChirpMentions(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	mentionsByID := make(map[uuid.UUID][]mentionResponse)
+	for _, mention := range mentions {
+		mentionsByID[mention.ChirpID] = append(mentionsByID[mention.ChirpID], mentionResponse{
+			UserID: mention.UserID,
+			Start:  mention.StartOffset,
+			End:    mention.EndOffset,
+		})
+	}
+	var likedByViewer map[uuid.UUID]bool
+	if viewer.Valid {
+		likedIDs, err := cfg.db.GetLikedChirpIDs(ctx, database.GetLikedChirpIDsParams{
+			UserID:   viewer.UUID,
+			ChirpIds: ids,
+		})
+		if err != nil {
+			return nil, err
+		}
+		likedByViewer = make(map[uuid.UUID]bool, len(likedIDs))
+		for _, id := range likedIDs {
+			likedByViewer[id] = true
+		}
+	}
+	for _, chirp := range chirps {
+		response := chirpResponse{
+			ID:           chirp.ID,
+			CreatedAt:    chirp.CreatedAt,
+			UpdatedAt:    chirp.UpdatedAt,
+			Body:         chirp.Body,
+			UserID:       chirp.UserID,
+			ReplyCount:   replyCountByID[chirp.ID],
+			LikeCount:    likeCountByID[chirp.ID],
+			RechirpCount: rechirpCountByID[chirp.ID],
+			Mentions:     mentionsByID[chirp.ID],
+		}
+		if response.Mentions == nil {
+			response.Mentions = []mentionResponse{}
+		}
+		if chirp.ReplyTo.Valid {
+			response.ReplyTo = &chirp.ReplyTo.UUID
+		}
+		if chirp.RechirpOf.Valid {
+			if rechirped, ok := embedded[chirp.RechirpOf.UUID]; ok {
+				response.RechirpedChirp = rechirped
+			} else {
+				response.RechirpedChirp = chirpTombstone{Deleted: true}
+			}
+		}
+		if chirp.IsQuote {
+			if quoted, ok := embedded[chirp.QuoteOf.UUID]; chirp.QuoteOf.Valid && ok {
+				response.QuotedChirp = quoted
+			} else {
+				response.QuotedChirp = chirpTombstone{Deleted: true}
+			}
+		}
+		if viewer.Valid {
+			likedByMe := likedByViewer[chirp.ID]
+			response.LikedByMe = &likedByMe
+		}
+		responses = append(responses, response)
+	}
+	return responses, nil
+}
+
+// writeChirpPage writes one page of a keyset query that was asked for limit+1 rows.
+func (cfg *apiConfig) writeChirpPage(w http.ResponseWriter, r *http.Request, chirps []database.Chirp, limit int32) {
+	response := chirpPageResponse{}
+	if len(chirps) > int(limit) {
+		chirps = chirps[:limit]
+		last := chirps[len(chirps)-1]
+		response.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+	}
+	responses, err := cfg.chirpResponses(r.Context(), cfg.viewerID(r), chirps)
+	if err != nil {
+		writeError(w, fmt.Sprintf("error retrieving chirps: %s", err), 400)
+		return
+	}
+	response.Chirps = responses
+	dat, _ := json.Marshal(response)
+	w.Write(dat)
+}
+
+// embeddedChirps loads the chirps that the given chirps rechirp or quote, keyed by ID, leaving
+// out those the viewer has blocked or muted.
+func (cfg *apiConfig) embeddedChirps(ctx context.Context, viewer uuid.NullUUID, chirps []database.Chirp) (map[uuid.UUID]embeddedChirpResponse, error) {
+	var ids []uuid.UUID
+	for _, chirp := range chirps {
+		if chirp.RechirpOf.Valid {
+			ids = append(ids, chirp.RechirpOf.UUID)
+		}
+		if chirp.QuoteOf.Valid {
+			ids = append(ids, chirp.QuoteOf.UUID)
+		}
+	}
+	embedded := make(map[uuid.UUID]embeddedChirpResponse, len(ids))
+	if len(ids) == 0 {
+		return embedded, nil
+	}
+	originals, err := cfg.db.GetChirpsByIDs(ctx, database.GetChirpsByIDsParams{
+		ChirpIds: ids,
+		ViewerID: viewer,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, original := range originals {
+		embedded[original.ID] = embeddedChirpResponse{
+			ID:        original.ID,
+			CreatedAt: original.CreatedAt,
+			UpdatedAt: original.UpdatedAt,
+			Body:      original.Body,
+			UserID:    original.UserID,
+		}
+	}
+	return embedded, nil
+}
+
+func (cfg *apiConfig) chirpResponse(ctx context.Context, viewer uuid.NullUUID, chirp database.Chirp) (chirpResponse, error) {
+	responses, err := cfg.chirpResponses(ctx, viewer, []database.Chirp{chirp})
+	if err != nil {
+		return chirpResponse{}, err
+	}
+	return responses[0], nil
+}
+
 func (cfg *apiConfig) createChirpHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Content-Type", "application/json")
@@ -34,27 +249,61 @@ func (cfg *apiConfig) createChirpHandler() http.Handler {
 			return
 		}
 		bearerToken, bearer_err := auth.GetBearerToken(r.Header)
-		tokenID, validation_err := auth.ValidateJWT(bearerToken, cfg.jwtSecret)
+		tokenID, validation_err := cfg.activeUserID(r.Context(), bearerToken)
 		if validation_err != nil || bearer_err != nil {
 			w.WriteHeader(401)
 			return
 		}
+		if !cfg.canPost(w, r, tokenID) {
+			return
+		}
 
+		cleanedBody, errs := cfg.validateChirpBody(requestBody.Body)
 		chirpParams := database.CreateChirpParams{
-			Body:   requestBody.Body,
+			Body:   cleanedBody,
 			UserID: tokenID,
 		}
-		chirp, err := cfg.db.CreateChirp(r.Context(), chirpParams)
+		if requestBody.ReplyTo != nil {
+			parent, err := cfg.db.GetVisibleChirp(r.Context(), database.GetVisibleChirpParams{ID: *requestBody.ReplyTo})
+			if err != nil {
+				errs.Add(validation.CodeNotFound, "reply_to", "the chirp being replied to does not exist")
+			} else if blocked, err := cfg.isBlockedBy(r.Context(), parent.UserID, tokenID); err != nil {
+				writeError(w, fmt.Sprintf("error checking blocks: %s", err), 500)
+				return
+			} else if blocked {
+				writeError(w, "you cannot reply to this user", 403)
+				return
+			}
+			chirpParams.ReplyTo = uuid.NullUUID{UUID: *requestBody.ReplyTo, Valid: true}
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+		var chirp database.Chirp
+		var notifications []database.Notification
+		err := cfg.withTx(r.Context(), func(q *database.Queries) error {
+			var err error
+			chirp, err = q.CreateChirp(r.Context(), chirpParams)
+			if err != nil {
+				return err
+			}
+			if err := cfg.indexChirp(r.Context(), q, chirp); err != nil {
+				return err
+			}
+			notifications, err = notifyChirp(r.Context(), q, chirp)
+			return err
+		})
 		if err != nil {
 			writeError(w, fmt.Sprintf("error creating chirp: %s", err), 400)
 			return
 		}
-		response := chirpResponse{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
+		cfg.publishChirpCreated(r.Context(), chirp)
+		cfg.publishNotifications(notifications)
+		response, err := cfg.chirpResponse(r.Context(), cfg.viewerID(r), chirp)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving chirp: %s", err), 400)
+			return
 		}
 		dat, _ := json.Marshal(response)
 		w.WriteHeader(201)
@@ -66,38 +315,44 @@ func (cfg *apiConfig) getChirpsHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Add("Content-Type", "application/json")
 		defer r.Body.Close()
-		authorID := r.URL.Query().Get("author_id")
-		sort := r.URL.Query().Get("sort")
-		var chirps []database.Chirp
-		var err error
-		if sort == "desc" {
-			if authorID != "" {
-				chirps, err = cfg.db.GetUserChirpsDesc(r.Context(), uuid.MustParse(authorID))
-			} else {
-				chirps, err = cfg.db.GetAllChirpsDesc(r.Context())
+		limit, cursor, err := parsePageParams(r)
+		if err != nil {
+			writeError(w, err.Error(), 400)
+			return
+		}
+		var authorID uuid.NullUUID
+		if authorIDStr := r.URL.Query().Get("author_id"); authorIDStr != "" {
+			parsed, err := uuid.Parse(authorIDStr)
+			if err != nil {
+				writeError(w, "invalid author_id", 400)
+				return
 			}
+			authorID = uuid.NullUUID{UUID: parsed, Valid: true}
+		}
+		// fetch one extra row to find out whether there is a next page
+		var chirps []database.Chirp
+		if r.URL.Query().Get("sort") == "desc" {
+			chirps, err = cfg.db.GetChirpsPageDesc(r.Context(), database.GetChirpsPageDescParams{
+				AuthorID:        authorID,
+				CursorCreatedAt: cursor.CreatedAt,
+				CursorID:        cursor.ID,
+				ViewerID:        cfg.viewerID(r),
+				PageLimit:       limit + 1,
+			})
 		} else {
-			if authorID != "" {
-				chirps, err = cfg.db.GetUserChirpsAsc(r.Context(), uuid.MustParse(authorID))
-			} else {
-				chirps, err = cfg.db.GetAllChirpsAsc(r.Context())
-			}
+			chirps, err = cfg.db.GetChirpsPageAsc(r.Context(), database.GetChirpsPageAscParams{
+				AuthorID:        authorID,
+				CursorCreatedAt: cursor.CreatedAt,
+				CursorID:        cursor.ID,
+				ViewerID:        cfg.viewerID(r),
+				PageLimit:       limit + 1,
+			})
 		}
 		if err != nil {
 			writeError(w, fmt.Sprintf("error retrieving chirps: %s", err), 400)
+			return
 		}
-		var responseArray []chirpResponse
-		for _, chirp := range chirps {
-			responseArray = append(responseArray, chirpResponse{
-				ID:        chirp.ID,
-				CreatedAt: chirp.CreatedAt,
-				UpdatedAt: chirp.UpdatedAt,
-				Body:      chirp.Body,
-				UserID:    chirp.UserID,
-			})
-		}
-		dat, _ := json.Marshal(responseArray)
-		w.Write(dat)
+		cfg.writeChirpPage(w, r, chirps, limit)
 	})
 }
 
@@ -106,17 +361,19 @@ func (cfg *apiConfig) getChirpHandler() http.Handler {
 		w.Header().Add("Content-Type", "application/json")
 		defer r.Body.Close()
 
-		chirp, err := cfg.db.GetChirp(r.Context(), uuid.MustParse(r.PathValue("chirpID")))
+		viewerID := cfg.viewerID(r)
+		chirp, err := cfg.db.GetVisibleChirp(r.Context(), database.GetVisibleChirpParams{
+			ID:       uuid.MustParse(r.PathValue("chirpID")),
+			ViewerID: viewerID,
+		})
 		if err != nil {
 			writeError(w, fmt.Sprintf("error retrieving chirp: %s", err), 404)
 			return
 		}
-		response := chirpResponse{
-			ID:        chirp.ID,
-			CreatedAt: chirp.CreatedAt,
-			UpdatedAt: chirp.UpdatedAt,
-			Body:      chirp.Body,
-			UserID:    chirp.UserID,
+		response, err := cfg.chirpResponse(r.Context(), viewerID, chirp)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving chirp: %s", err), 400)
+			return
 		}
 		dat, _ := json.Marshal(response)
 		w.WriteHeader(200)
@@ -132,7 +389,7 @@ func (cfg *apiConfig) deleteChirpHandler() http.Handler {
 			writeError(w, "could not obtain a bearer token", 401)
 			return
 		}
-		userID, validationErr := auth.ValidateJWT(bearerToken, cfg.jwtSecret)
+		userID, validationErr := cfg.activeUserID(r.Context(), bearerToken)
 		if validationErr != nil {
 			w.WriteHeader(401)
 			return
@@ -146,10 +403,263 @@ func (cfg *apiConfig) deleteChirpHandler() http.Handler {
 			writeError(w, "user did not author that chirp", 403)
 			return
 		}
-		deleteErr := cfg.db.DeleteChirp(r.Context(), uuid.MustParse(r.PathValue("chirpID")))
+		// rechirps would go with the chirp via ON DELETE CASCADE; deleting them first tells us which to publish
+		var rechirps []database.Chirp
+		deleteErr := cfg.withTx(r.Context(), func(q *database.Queries) error {
+			var err error
+			rechirps, err = q.DeleteRechirpsOf(r.Context(), uuid.NullUUID{UUID: chirp.ID, Valid: true})
+			if err != nil {
+				return err
+			}
+			return q.DeleteChirp(r.Context(), chirp.ID)
+		})
 		if deleteErr != nil {
 			writeError(w, "error deleting chirp", 404)
+			return
+		}
+		cfg.publishChirpDeleted(chirp)
+		for _, rechirp := range rechirps {
+			cfg.publishChirpDeleted(rechirp)
 		}
 		w.WriteHeader(204)
 	})
 }
+
+func (cfg *apiConfig) editChirpHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		bearerToken, bearerErr := auth.GetBearerToken(r.Header)
+		if bearerErr != nil {
+			writeError(w, "could not obtain a bearer token", 401)
+			return
+		}
+		userID, validationErr := cfg.activeUserID(r.Context(), bearerToken)
+		if validationErr != nil {
+			w.WriteHeader(401)
+			return
+		}
+		if !cfg.canPost(w, r, userID) {
+			return
+		}
+		chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+		if err != nil {
+			writeError(w, "invalid chirp id", 404)
+			return
+		}
+		chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+		if err != nil {
+			writeError(w, "error retrieving chirp", 404)
+			return
+		}
+		if chirp.UserID != userID {
+			writeError(w, "user did not author that chirp", 403)
+			return
+		}
+		if chirp.RechirpOf.Valid {
+			writeError(w, "rechirps cannot be edited", 400)
+			return
+		}
+		var requestBody chirpRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			writeError(w, fmt.Sprintf("error decoding json: %s", err), 400)
+			return
+		}
+		cleanedBody, errs := cfg.validateChirpBody(requestBody.Body)
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+		if cleanedBody != chirp.Body {
+			var notifications []database.Notification
+			chirp, notifications, err = cfg.updateChirpWithRevision(r.Context(), chirp.ID, cleanedBody)
+			if err != nil {
+				writeError(w, fmt.Sprintf("error editing chirp: %s", err), 400)
+				return
+			}
+			cfg.publishNotifications(notifications)
+		}
+		response, err := cfg.chirpResponse(r.Context(), cfg.viewerID(r), chirp)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving chirp: %s", err), 400)
+			return
+		}
+		dat, _ := json.Marshal(response)
+		w.WriteHeader(200)
+		w.Write(dat)
+	})
+}
+
+// indexChirp (re)builds everything derived from the chirp's body.
+func (cfg *apiConfig) indexChirp(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	if err := indexHashtags(ctx, q, chirp); err != nil {
+		return err
+	}
+	if err := indexMentions(ctx, q, chirp); err != nil {
+		return err
+	}
+	return cfg.flagChirp(ctx, q, chirp)
+}
+
+// updateChirpWithRevision archives the current body of the chirp and replaces it in a single transaction.
+// The chirp is locked before it is archived, so concurrent edits each archive the body they replace.
+// It returns the notifications for users the new body mentions for the first time.
+func (cfg *apiConfig) updateChirpWithRevision(ctx context.Context, chirpID uuid.UUID, body string) (database.Chirp, []database.Notification, error) {
+	var updated database.Chirp
+	var notifications []database.Notification
+	err := cfg.withTx(ctx, func(q *database.Queries) error {
+		chirp, err := q.GetChirpForUpdate(ctx, chirpID)
+		if err != nil {
+			return err
+		}
+		previous, err := q.GetChirpMentions(ctx, []uuid.UUID{chirp.ID})
+		if err != nil {
+			return err
+		}
+		_, err = q.CreateChirpRevision(ctx, database.CreateChirpRevisionParams{
+			ChirpID:   chirp.ID,
+			Body:      chirp.Body,
+			CreatedAt: chirp.UpdatedAt,
+		})
+		if err != nil {
+			return err
+		}
+		updated, err = q.UpdateChirp(ctx, database.UpdateChirpParams{
+			ID:   chirp.ID,
+			Body: body,
+		})
+		if err != nil {
+			return err
+		}
+		if err := cfg.indexChirp(ctx, q, updated); err != nil {
+			return err
+		}
+		notifications, err = notifyEditedChirp(ctx, q, updated, previous)
+		return err
+	})
+	return updated, notifications, err
+}
+
+func (cfg *apiConfig) getChirpHistoryHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+		if err != nil {
+			writeError(w, "invalid chirp id", 404)
+			return
+		}
+		viewerID := cfg.viewerID(r)
+		chirp, err := cfg.db.GetVisibleChirp(r.Context(), database.GetVisibleChirpParams{ID: chirpID, ViewerID: viewerID})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving chirp: %s", err), 404)
+			return
+		}
+		revisions, err := cfg.db.GetChirpRevisions(r.Context(), chirpID)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving chirp history: %s", err), 400)
+			return
+		}
+		current, err := cfg.chirpResponse(r.Context(), viewerID, chirp)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving chirp: %s", err), 400)
+			return
+		}
+		response := chirpHistoryResponse{
+			Chirp:     current,
+			Revisions: []chirpRevisionResponse{},
+		}
+		for _, revision := range revisions {
+			response.Revisions = append(response.Revisions, chirpRevisionResponse{
+				Body:       revision.Body,
+				CreatedAt:  revision.CreatedAt,
+				ReplacedAt: revision.ReplacedAt,
+			})
+		}
+		dat, _ := json.Marshal(response)
+		w.Write(dat)
+	})
+}
+
+func (cfg *apiConfig) getRepliesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+		if err != nil {
+			writeError(w, "invalid chirp id", 404)
+			return
+		}
+		limit, cursor, err := parsePageParams(r)
+		if err != nil {
+			writeError(w, err.Error(), 400)
+			return
+		}
+		viewerID := cfg.viewerID(r)
+		_, err = cfg.db.GetVisibleChirp(r.Context(), database.GetVisibleChirpParams{ID: chirpID, ViewerID: viewerID})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving chirp: %s", err), 404)
+			return
+		}
+		replies, err := cfg.db.GetRepliesPage(r.Context(), database.GetRepliesPageParams{
+			ReplyTo:         uuid.NullUUID{UUID: chirpID, Valid: true},
+			CursorCreatedAt: cursor.CreatedAt,
+			CursorID:        cursor.ID,
+			ViewerID:        viewerID,
+			PageLimit:       limit + 1,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving replies: %s", err), 400)
+			return
+		}
+		cfg.writeChirpPage(w, r, replies, limit)
+	})
+}
+
+func (cfg *apiConfig) getThreadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+		if err != nil {
+			writeError(w, "invalid chirp id", 404)
+			return
+		}
+		viewerID := cfg.viewerID(r)
+		chirp, err := cfg.db.GetVisibleChirp(r.Context(), database.GetVisibleChirpParams{ID: chirpID, ViewerID: viewerID})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving chirp: %s", err), 404)
+			return
+		}
+		ancestors, err := cfg.db.GetChirpAncestors(r.Context(), database.GetChirpAncestorsParams{
+			ChirpID:  chirpID,
+			ViewerID: viewerID,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving thread: %s", err), 400)
+			return
+		}
+		descendants, err := cfg.db.GetChirpDescendants(r.Context(), database.GetChirpDescendantsParams{
+			ChirpID:    chirpID,
+			ViewerID:   viewerID,
+			MaxResults: maxThreadDescendants,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving thread: %s", err), 400)
+			return
+		}
+		thread := append(append(ancestors, chirp), descendants...)
+		responses, err := cfg.chirpResponses(r.Context(), viewerID, thread)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving thread: %s", err), 400)
+			return
+		}
+		response := chirpThreadResponse{
+			Ancestors:   responses[:len(ancestors)],
+			Chirp:       responses[len(ancestors)],
+			Descendants: responses[len(ancestors)+1:],
+		}
+		dat, _ := json.Marshal(response)
+		w.Write(dat)
+	})
+}
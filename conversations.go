@@ -0,0 +1,493 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/Kurlgargyey/chirpy/internal/validation"
+	"github.com/google/uuid"
+)
+
+type conversationRequestBody struct {
+	ParticipantIDs []uuid.UUID `json:"participant_ids"`
+}
+
+type messageRequestBody struct {
+	Body *string `json:"body"`
+}
+
+type participantResponse struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	JoinedAt   time.Time  `json:"joined_at"`
+	LastReadAt *time.Time `json:"last_read_at"`
+}
+
+type conversationResponse struct {
+	ID           uuid.UUID             `json:"id"`
+	CreatedAt    time.Time             `json:"created_at"`
+	UpdatedAt    time.Time             `json:"updated_at"`
+	IsGroup      bool                  `json:"is_group"`
+	Participants []participantResponse `json:"participants"`
+	UnreadCount  int64                 `json:"unread_count"`
+}
+
+type conversationPageResponse struct {
+	Conversations []conversationResponse `json:"conversations"`
+	NextCursor    string                 `json:"next_cursor,omitempty"`
+}
+
+type messageResponse struct {
+	ID             uuid.UUID   `json:"id"`
+	ConversationID uuid.UUID   `json:"conversation_id"`
+	SenderID       uuid.UUID   `json:"sender_id"`
+	Body           string      `json:"body"`
+	CreatedAt      time.Time   `json:"created_at"`
+	ReadBy         []uuid.UUID `json:"read_by"`
+}
+
+type messagePageResponse struct {
+	Messages   []messageResponse `json:"messages"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+func newParticipantResponses(participants []database.ConversationParticipant) []participantResponse {
+	responses := make([]participantResponse, 0, len(participants))
+	for _, participant := range participants {
+		response := participantResponse{
+			UserID:   participant.UserID,
+			JoinedAt: participant.JoinedAt,
+		}
+		if participant.LastReadAt.Valid {
+			response.LastReadAt = &participant.LastReadAt.Time
+		}
+		responses = append(responses, response)
+	}
+	return responses
+}
+
+// newMessageResponse lists as readers the other participants whose read receipt covers the message.
+func newMessageResponse(message database.Message, participants []database.ConversationParticipant) messageResponse {
+	response := messageResponse{
+		ID:             message.ID,
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		Body:           message.Body,
+		CreatedAt:      message.CreatedAt,
+		ReadBy:         []uuid.UUID{},
+	}
+	for _, participant := range participants {
+		if participant.UserID == message.SenderID || !participant.LastReadAt.Valid {
+			continue
+		}
+		if !participant.LastReadAt.Time.Before(message.CreatedAt) {
+			response.ReadBy = append(response.ReadBy, participant.UserID)
+		}
+	}
+	return response
+}
+
+// conversationForParticipant loads the {conversationID} conversation and its participants.
+// Callers who are not participants get a 404, so they cannot probe which conversations exist.
+// It writes the error response itself and returns ok=false on failure.
+func (cfg *apiConfig) conversationForParticipant(w http.ResponseWriter, r *http.Request, userID uuid.UUID) (database.Conversation, []database.ConversationParticipant, bool) {
+	conversationID, err := uuid.Parse(r.PathValue("conversationID"))
+	if err != nil {
+		writeError(w, "invalid conversation id", 404)
+		return database.Conversation{}, nil, false
+	}
+	conversation, err := cfg.db.GetConversation(r.Context(), conversationID)
+	if err != nil {
+		writeError(w, "conversation not found", 404)
+		return database.Conversation{}, nil, false
+	}
+	participants, err := cfg.db.GetConversationParticipants(r.Context(), []uuid.UUID{conversationID})
+	if err != nil {
+		writeError(w, fmt.Sprintf("error retrieving conversation: %s", err), 400)
+		return database.Conversation{}, nil, false
+	}
+	for _, participant := range participants {
+		if participant.UserID == userID {
+			return conversation, participants, true
+		}
+	}
+	writeError(w, "conversation not found", 404)
+	return database.Conversation{}, nil, false
+}
+
+// messageRecipientsBlock reports whether any other participant of a conversation has blocked
+// the sender.
+func (cfg *apiConfig) messageRecipientsBlock(ctx context.Context, senderID uuid.UUID, recipientIDs []uuid.UUID) (bool, error) {
+	return cfg.db.IsBlockedByAny(ctx, database.IsBlockedByAnyParams{
+		BlockedID:  senderID,
+		BlockerIds: recipientIDs,
+	})
+}
+
+func (cfg *apiConfig) createConversationHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		var requestBody conversationRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			writeError(w, fmt.Sprintf("error decoding json: %s", err), 400)
+			return
+		}
+		if !cfg.canPost(w, r, userID) {
+			return
+		}
+
+		var errs validation.Errors
+		var recipientIDs []uuid.UUID
+		seen := map[uuid.UUID]bool{userID: true}
+		for _, id := range requestBody.ParticipantIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+			recipient, err := cfg.db.GetUserByID(r.Context(), id)
+			if err != nil || recipient.DeletedAt.Valid {
+				errs.Add(validation.CodeNotFound, "participant_ids", fmt.Sprintf("user %s does not exist", id))
+				continue
+			}
+			recipientIDs = append(recipientIDs, id)
+		}
+		if len(recipientIDs) == 0 && len(errs) == 0 {
+			errs.Add(validation.CodeRequired, "participant_ids", "a conversation needs at least one other participant")
+		}
+		if len(recipientIDs)+1 > validation.MaxConversationParticipants {
+			errs.Add(validation.CodeInvalid, "participant_ids", fmt.Sprintf("a conversation can have at most %d participants", validation.MaxConversationParticipants))
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+		blocked, err := cfg.messageRecipientsBlock(r.Context(), userID, recipientIDs)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error checking blocks: %s", err), 500)
+			return
+		}
+		if blocked {
+			writeError(w, "you cannot message this user", 403)
+			return
+		}
+
+		// a one-to-one conversation between two users is reused rather than duplicated
+		if len(recipientIDs) == 1 {
+			existing, err := cfg.db.GetDirectConversation(r.Context(), database.GetDirectConversationParams{
+				UserID:      userID,
+				OtherUserID: recipientIDs[0],
+			})
+			if err == nil {
+				cfg.writeConversation(w, r, existing, 200)
+				return
+			}
+			if !errors.Is(err, sql.ErrNoRows) {
+				writeError(w, fmt.Sprintf("error retrieving conversation: %s", err), 400)
+				return
+			}
+		}
+
+		var conversation database.Conversation
+		err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+			var err error
+			conversation, err = q.CreateConversation(r.Context(), len(recipientIDs) > 1)
+			if err != nil {
+				return err
+			}
+			for _, id := range append([]uuid.UUID{userID}, recipientIDs...) {
+				err := q.AddConversationParticipant(r.Context(), database.AddConversationParticipantParams{
+					ConversationID: conversation.ID,
+					UserID:         id,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error creating conversation: %s", err), 400)
+			return
+		}
+		cfg.writeConversation(w, r, conversation, 201)
+	})
+}
+
+// writeConversation responds with a conversation the caller has just created or opened, so
+// there is nothing unread in it for them.
+func (cfg *apiConfig) writeConversation(w http.ResponseWriter, r *http.Request, conversation database.Conversation, code int) {
+	participants, err := cfg.db.GetConversationParticipants(r.Context(), []uuid.UUID{conversation.ID})
+	if err != nil {
+		writeError(w, fmt.Sprintf("error retrieving conversation: %s", err), 400)
+		return
+	}
+	dat, _ := json.Marshal(conversationResponse{
+		ID:           conversation.ID,
+		CreatedAt:    conversation.CreatedAt,
+		UpdatedAt:    conversation.UpdatedAt,
+		IsGroup:      conversation.IsGroup,
+		Participants: newParticipantResponses(participants),
+	})
+	w.WriteHeader(code)
+	w.Write(dat)
+}
+
+// conversations page on their last activity as of the first page, which the cursor carries as a
+// snapshot, so new messages don't move a conversation between pages
+func encodeConversationCursor(snapshot, activeAt time.Time, id uuid.UUID) string {
+	raw := fmt.Sprintf("%s|%s|%s", snapshot.UTC().Format(time.RFC3339Nano), activeAt.UTC().Format(time.RFC3339Nano), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeConversationCursor(cursor string) (sql.NullTime, pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return sql.NullTime{}, pageCursor{}, fmt.Errorf("malformed cursor")
+	}
+	parts := strings.Split(string(raw), "|")
+	if len(parts) != 3 {
+		return sql.NullTime{}, pageCursor{}, fmt.Errorf("malformed cursor")
+	}
+	snapshot, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return sql.NullTime{}, pageCursor{}, fmt.Errorf("malformed cursor")
+	}
+	activeAt, err := time.Parse(time.RFC3339Nano, parts[1])
+	if err != nil {
+		return sql.NullTime{}, pageCursor{}, fmt.Errorf("malformed cursor")
+	}
+	id, err := uuid.Parse(parts[2])
+	if err != nil {
+		return sql.NullTime{}, pageCursor{}, fmt.Errorf("malformed cursor")
+	}
+	return sql.NullTime{Time: snapshot, Valid: true}, pageCursor{
+		CreatedAt: sql.NullTime{Time: activeAt, Valid: true},
+		ID:        uuid.NullUUID{UUID: id, Valid: true},
+	}, nil
+}
+
+// getConversationsHandler lists the caller's conversations, most recently active first.
+func (cfg *apiConfig) getConversationsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		limit, err := parsePageLimit(r)
+		if err != nil {
+			writeError(w, err.Error(), 400)
+			return
+		}
+		var snapshot sql.NullTime
+		var cursor pageCursor
+		if cursorStr := r.URL.Query().Get("cursor"); cursorStr != "" {
+			snapshot, cursor, err = decodeConversationCursor(cursorStr)
+			if err != nil {
+				writeError(w, err.Error(), 400)
+				return
+			}
+		}
+		conversations, err := cfg.db.GetUserConversationsPage(r.Context(), database.GetUserConversationsPageParams{
+			UserID:         userID,
+			Snapshot:       snapshot,
+			CursorActiveAt: cursor.CreatedAt,
+			CursorID:       cursor.ID,
+			PageLimit:      limit + 1,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving conversations: %s", err), 400)
+			return
+		}
+		response := conversationPageResponse{Conversations: []conversationResponse{}}
+		if len(conversations) > int(limit) {
+			// the first page is ordered by activity up to now, so its newest activity is the snapshot
+			if !snapshot.Valid {
+				snapshot = sql.NullTime{Time: conversations[0].ActiveAt, Valid: true}
+			}
+			conversations = conversations[:limit]
+			last := conversations[len(conversations)-1]
+			response.NextCursor = encodeConversationCursor(snapshot.Time, last.ActiveAt, last.ID)
+		}
+		ids := make([]uuid.UUID, 0, len(conversations))
+		for _, conversation := range conversations {
+			ids = append(ids, conversation.ID)
+		}
+		participants, err := cfg.db.GetConversationParticipants(r.Context(), ids)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving conversations: %s", err), 400)
+			return
+		}
+		byConversation := make(map[uuid.UUID][]database.ConversationParticipant, len(conversations))
+		for _, participant := range participants {
+			byConversation[participant.ConversationID] = append(byConversation[participant.ConversationID], participant)
+		}
+		for _, conversation := range conversations {
+			response.Conversations = append(response.Conversations, conversationResponse{
+				ID:           conversation.ID,
+				CreatedAt:    conversation.CreatedAt,
+				UpdatedAt:    conversation.UpdatedAt,
+				IsGroup:      conversation.IsGroup,
+				Participants: newParticipantResponses(byConversation[conversation.ID]),
+				UnreadCount:  conversation.UnreadCount,
+			})
+		}
+		dat, _ := json.Marshal(response)
+		w.Write(dat)
+	})
+}
+
+func (cfg *apiConfig) getMessagesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		conversation, participants, ok := cfg.conversationForParticipant(w, r, userID)
+		if !ok {
+			return
+		}
+		limit, cursor, err := parsePageParams(r)
+		if err != nil {
+			writeError(w, err.Error(), 400)
+			return
+		}
+		messages, err := cfg.db.GetMessagesPage(r.Context(), database.GetMessagesPageParams{
+			ConversationID:  conversation.ID,
+			CursorCreatedAt: cursor.CreatedAt,
+			CursorID:        cursor.ID,
+			PageLimit:       limit + 1,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving messages: %s", err), 400)
+			return
+		}
+		response := messagePageResponse{Messages: []messageResponse{}}
+		if len(messages) > int(limit) {
+			messages = messages[:limit]
+			last := messages[len(messages)-1]
+			response.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+		}
+		for _, message := range messages {
+			response.Messages = append(response.Messages, newMessageResponse(message, participants))
+		}
+		dat, _ := json.Marshal(response)
+		w.Write(dat)
+	})
+}
+
+func (cfg *apiConfig) sendMessageHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		conversation, participants, ok := cfg.conversationForParticipant(w, r, userID)
+		if !ok {
+			return
+		}
+		var requestBody messageRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			writeError(w, fmt.Sprintf("error decoding json: %s", err), 400)
+			return
+		}
+		if !cfg.canPost(w, r, userID) {
+			return
+		}
+		body, errs := validation.MessageBody(requestBody.Body)
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+		var recipientIDs []uuid.UUID
+		for _, participant := range participants {
+			if participant.UserID != userID {
+				recipientIDs = append(recipientIDs, participant.UserID)
+			}
+		}
+		blocked, err := cfg.messageRecipientsBlock(r.Context(), userID, recipientIDs)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error checking blocks: %s", err), 500)
+			return
+		}
+		if blocked {
+			writeError(w, "you cannot message this user", 403)
+			return
+		}
+
+		var message database.Message
+		err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+			var err error
+			message, err = q.CreateMessage(r.Context(), database.CreateMessageParams{
+				ConversationID: conversation.ID,
+				SenderID:       userID,
+				Body:           body,
+			})
+			if err != nil {
+				return err
+			}
+			if err := q.TouchConversation(r.Context(), conversation.ID); err != nil {
+				return err
+			}
+			// senders have read everything up to their own message
+			return q.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+				ConversationID: conversation.ID,
+				UserID:         userID,
+			})
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error sending message: %s", err), 400)
+			return
+		}
+		dat, _ := json.Marshal(newMessageResponse(message, nil))
+		w.WriteHeader(201)
+		w.Write(dat)
+	})
+}
+
+// markConversationReadHandler records a read receipt covering every message sent so far.
+func (cfg *apiConfig) markConversationReadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		conversation, _, ok := cfg.conversationForParticipant(w, r, userID)
+		if !ok {
+			return
+		}
+		err = cfg.db.MarkConversationRead(r.Context(), database.MarkConversationReadParams{
+			ConversationID: conversation.ID,
+			UserID:         userID,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error marking conversation read: %s", err), 400)
+			return
+		}
+		w.WriteHeader(204)
+	})
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/lib/pq"
+)
+
+// withTx runs fn against a transaction that is committed if fn succeeds and rolled back otherwise.
+func (cfg *apiConfig) withTx(ctx context.Context, fn func(q *database.Queries) error) error {
+	tx, err := cfg.conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if err := fn(cfg.db.WithTx(tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// isUniqueViolation reports whether err was caused by the named unique constraint or index.
+func isUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == constraint
+}
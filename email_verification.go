@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Kurlgargyey/chirpy/internal/auth"
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/Kurlgargyey/chirpy/internal/mail"
+	"github.com/google/uuid"
+)
+
+const emailVerificationLifetime = 48 * time.Hour
+
+var errEmailChanged = errors.New("email changed since the token was sent")
+
+type verifyEmailRequestBody struct {
+	Token string `json:"token"`
+}
+
+// sendVerificationEmail mails a token proving ownership of the user's current address.
+func (cfg *apiConfig) sendVerificationEmail(ctx context.Context, user database.User) error {
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		return err
+	}
+	err = cfg.db.CreateEmailVerificationToken(ctx, database.CreateEmailVerificationTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		Email:     user.Email,
+		ExpiresAt: time.Now().UTC().Add(emailVerificationLifetime),
+	})
+	if err != nil {
+		return err
+	}
+	return cfg.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Verify your Chirpy email address",
+		Body: fmt.Sprintf("Please confirm this is your email address by submitting the following token:\n\n"+
+			"    %s\n\n"+
+			"It expires in 48 hours. If you didn't sign up for Chirpy, you can ignore this email.\n", token),
+	})
+}
+
+// canPost enforces the REQUIRE_VERIFIED_EMAIL policy, writing a 403 if the user may not post yet.
+func (cfg *apiConfig) canPost(w http.ResponseWriter, r *http.Request, userID uuid.UUID) bool {
+	if !cfg.requireVerifiedEmail {
+		return true
+	}
+	user, err := cfg.db.GetUserByID(r.Context(), userID)
+	if err != nil {
+		writeError(w, fmt.Sprintf("error retrieving user: %s", err), 400)
+		return false
+	}
+	if !user.EmailVerifiedAt.Valid {
+		writeError(w, "verify your email address before posting", 403)
+		return false
+	}
+	return true
+}
+
+func (cfg *apiConfig) verifyEmailHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		var requestBody verifyEmailRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			writeError(w, fmt.Sprintf("error decoding json: %s", err), 400)
+			return
+		}
+		err := cfg.withTx(r.Context(), func(q *database.Queries) error {
+			token, err := q.ConsumeEmailVerificationToken(r.Context(), database.ConsumeEmailVerificationTokenParams{
+				TokenHash: auth.HashToken(requestBody.Token),
+				ExpiresAt: time.Now().UTC(),
+			})
+			if err != nil {
+				return err
+			}
+			verified, err := q.MarkEmailVerified(r.Context(), database.MarkEmailVerifiedParams{
+				ID:    token.UserID,
+				Email: token.Email,
+			})
+			if err != nil {
+				return err
+			}
+			if verified == 0 {
+				return errEmailChanged
+			}
+			return nil
+		})
+		if errors.Is(err, sql.ErrNoRows) || errors.Is(err, errEmailChanged) {
+			writeError(w, "invalid or expired verification token", 400)
+			return
+		}
+		if err != nil {
+			writeError(w, fmt.Sprintf("error verifying email: %s", err), 400)
+			return
+		}
+		w.WriteHeader(204)
+	})
+}
+
+func (cfg *apiConfig) resendVerificationHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving user: %s", err), 400)
+			return
+		}
+		if user.EmailVerifiedAt.Valid {
+			writeError(w, "email address is already verified", 409)
+			return
+		}
+		if err := cfg.sendVerificationEmail(r.Context(), user); err != nil {
+			writeError(w, fmt.Sprintf("error sending verification email: %s", err), 500)
+			return
+		}
+		w.WriteHeader(202)
+	})
+}
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type followResponse struct {
+	UserID    uuid.UUID `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type followPageResponse struct {
+	Users      []followResponse `json:"users"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) followUserHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		followeeID, err := uuid.Parse(r.PathValue("userID"))
+		if err != nil {
+			writeError(w, "invalid user id", 404)
+			return
+		}
+		if followeeID == userID {
+			writeError(w, "users cannot follow themselves", 400)
+			return
+		}
+		if _, err := cfg.db.GetUserByID(r.Context(), followeeID); err != nil {
+			writeError(w, "user not found", 404)
+			return
+		}
+		blocked, err := cfg.isBlockedBy(r.Context(), followeeID, userID)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error checking blocks: %s", err), 500)
+			return
+		}
+		if blocked {
+			writeError(w, "you cannot follow this user", 403)
+			return
+		}
+		err = cfg.db.FollowUser(r.Context(), database.FollowUserParams{
+			FollowerID: userID,
+			FolloweeID: followeeID,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error following user: %s", err), 400)
+			return
+		}
+		w.WriteHeader(204)
+	})
+}
+
+func (cfg *apiConfig) unfollowUserHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		followeeID, err := uuid.Parse(r.PathValue("userID"))
+		if err != nil {
+			writeError(w, "invalid user id", 404)
+			return
+		}
+		err = cfg.db.UnfollowUser(r.Context(), database.UnfollowUserParams{
+			FollowerID: userID,
+			FolloweeID: followeeID,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error unfollowing user: %s", err), 400)
+			return
+		}
+		w.WriteHeader(204)
+	})
+}
+
+func (cfg *apiConfig) getFollowersHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := uuid.Parse(r.PathValue("userID"))
+		if err != nil {
+			writeError(w, "invalid user id", 404)
+			return
+		}
+		limit, cursor, err := parsePageParams(r)
+		if err != nil {
+			writeError(w, err.Error(), 400)
+			return
+		}
+		follows, err := cfg.db.GetFollowersPage(r.Context(), database.GetFollowersPageParams{
+			UserID:          userID,
+			CursorCreatedAt: cursor.CreatedAt,
+			CursorID:        cursor.ID,
+			PageLimit:       limit + 1,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving followers: %s", err), 400)
+			return
+		}
+		writeFollowPage(w, follows, limit, func(follow database.Follow) uuid.UUID {
+			return follow.FollowerID
+		})
+	})
+}
+
+func (cfg *apiConfig) getFollowingHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := uuid.Parse(r.PathValue("userID"))
+		if err != nil {
+			writeError(w, "invalid user id", 404)
+			return
+		}
+		limit, cursor, err := parsePageParams(r)
+		if err != nil {
+			writeError(w, err.Error(), 400)
+			return
+		}
+		follows, err := cfg.db.GetFollowingPage(r.Context(), database.GetFollowingPageParams{
+			UserID:          userID,
+			CursorCreatedAt: cursor.CreatedAt,
+			CursorID:        cursor.ID,
+			PageLimit:       limit + 1,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving followed users: %s", err), 400)
+			return
+		}
+		writeFollowPage(w, follows, limit, func(follow database.Follow) uuid.UUID {
+			return follow.FolloweeID
+		})
+	})
+}
+
+// writeFollowPage lists the user on the other side of each follow, which otherUser picks out.
+func writeFollowPage(w http.ResponseWriter, follows []database.Follow, limit int32, otherUser func(database.Follow) uuid.UUID) {
+	response := followPageResponse{Users: []followResponse{}}
+	if len(follows) > int(limit) {
+		follows = follows[:limit]
+		last := follows[len(follows)-1]
+		response.NextCursor = encodeCursor(last.CreatedAt, otherUser(last))
+	}
+	for _, follow := range follows {
+		response.Users = append(response.Users, followResponse{
+			UserID:    otherUser(follow),
+			CreatedAt: follow.CreatedAt,
+		})
+	}
+	dat, _ := json.Marshal(response)
+	w.Write(dat)
+}
+
+func (cfg *apiConfig) timelineHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		limit, cursor, err := parsePageParams(r)
+		if err != nil {
+			writeError(w, err.Error(), 400)
+			return
+		}
+		chirps, err := cfg.db.GetTimelinePage(r.Context(), database.GetTimelinePageParams{
+			UserID:          userID,
+			CursorCreatedAt: cursor.CreatedAt,
+			CursorID:        cursor.ID,
+			PageLimit:       limit + 1,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving timeline: %s", err), 400)
+			return
+		}
+		cfg.writeChirpPage(w, r, chirps, limit)
+	})
+}
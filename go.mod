@@ -9,6 +9,11 @@ require (
 )
 
 require (
+	github.com/coder/websocket v1.8.14
 	github.com/golang-jwt/jwt v3.2.2+incompatible
+	github.com/pquerna/otp v1.5.0
+	github.com/rivo/uniseg v0.4.7
 	golang.org/x/crypto v0.29.0
 )
+
+require github.com/boombuler/barcode v1.0.1-0.20190219062509-6c824513bacc // indirect
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Kurlgargyey/chirpy/internal/chirptext"
+	"github.com/Kurlgargyey/chirpy/internal/database"
+)
+
+const (
+	defaultTrendingWindow = 24 * time.Hour
+	maxTrendingWindow     = 7 * 24 * time.Hour
+	defaultTrendingLimit  = 10
+	maxTrendingLimit      = 50
+)
+
+type trendingHashtagResponse struct {
+	Tag        string `json:"tag"`
+	ChirpCount int64  `json:"chirp_count"`
+}
+
+type trendingHashtagsResponse struct {
+	Since    time.Time                 `json:"since"`
+	Hashtags []trendingHashtagResponse `json:"hashtags"`
+}
+
+// indexHashtags replaces the hashtags recorded for the chirp with the ones in its body.
+func indexHashtags(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	if err := q.UntagChirp(ctx, chirp.ID); err != nil {
+		return err
+	}
+	for _, tag := range chirptext.ExtractHashtags(chirp.Body) {
+		hashtag, err := q.UpsertHashtag(ctx, tag)
+		if err != nil {
+			return err
+		}
+		err = q.TagChirp(ctx, database.TagChirpParams{
+			ChirpID:   chirp.ID,
+			HashtagID: hashtag.ID,
+			CreatedAt: chirp.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) getHashtagChirpsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		limit, cursor, err := parsePageParams(r)
+		if err != nil {
+			writeError(w, err.Error(), 400)
+			return
+		}
+		chirps, err := cfg.db.GetHashtagChirpsPage(r.Context(), database.GetHashtagChirpsPageParams{
+			Tag:             chirptext.NormalizeHashtag(r.PathValue("tag")),
+			CursorCreatedAt: cursor.CreatedAt,
+			CursorID:        cursor.ID,
+			ViewerID:        cfg.viewerID(r),
+			PageLimit:       limit + 1,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving chirps: %s", err), 400)
+			return
+		}
+		cfg.writeChirpPage(w, r, chirps, limit)
+	})
+}
+
+func (cfg *apiConfig) trendingHashtagsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		window := defaultTrendingWindow
+		if windowStr := r.URL.Query().Get("window"); windowStr != "" {
+			parsed, err := time.ParseDuration(windowStr)
+			if err != nil || parsed <= 0 {
+				writeError(w, "window must be a positive duration such as 6h", 400)
+				return
+			}
+			window = min(parsed, maxTrendingWindow)
+		}
+		limit := defaultTrendingLimit
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			parsed, err := strconv.Atoi(limitStr)
+			if err != nil || parsed < 1 {
+				writeError(w, "limit must be a positive integer", 400)
+				return
+			}
+			limit = min(parsed, maxTrendingLimit)
+		}
+		since := time.Now().UTC().Add(-window)
+		trending, err := cfg.db.GetTrendingHashtags(r.Context(), database.GetTrendingHashtagsParams{
+			Since:      since,
+			MaxResults: int32(limit),
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving trending hashtags: %s", err), 400)
+			return
+		}
+		response := trendingHashtagsResponse{
+			Since:    since,
+			Hashtags: []trendingHashtagResponse{},
+		}
+		for _, row := range trending {
+			response.Hashtags = append(response.Hashtags, trendingHashtagResponse{
+				Tag:        row.Tag,
+				ChirpCount: row.ChirpCount,
+			})
+		}
+		dat, _ := json.Marshal(response)
+		w.Write(dat)
+	})
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"sync"
+	"time"
+)
+
+// AttemptLimiter counts attempts per key, such as a user or an MFA token. An attempt counts
+// until it is Reset, so only failed ones add up. A key that reaches the maximum is locked until
+// the window that started with its first attempt ends.
+type AttemptLimiter struct {
+	max    int
+	window time.Duration
+	now    func() time.Time
+
+	mu     sync.Mutex
+	counts map[string]attempts
+}
+
+type attempts struct {
+	count int
+	since time.Time
+}
+
+func NewAttemptLimiter(max int, window time.Duration) *AttemptLimiter {
+	return &AttemptLimiter{
+		max:    max,
+		window: window,
+		now:    time.Now,
+		counts: map[string]attempts{},
+	}
+}
+
+// Reserve records an attempt against every key, unless one of them is locked, and reports
+// whether it did. Checking and counting together keeps parallel attempts from all passing the
+// check before any of them is counted.
+func (l *AttemptLimiter) Reserve(keys ...string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	// expired entries are swept here rather than on a timer
+	for key, a := range l.counts {
+		if now.Sub(a.since) >= l.window {
+			delete(l.counts, key)
+		}
+	}
+	for _, key := range keys {
+		if l.counts[key].count >= l.max {
+			return false
+		}
+	}
+	for _, key := range keys {
+		a, ok := l.counts[key]
+		if !ok {
+			a.since = now
+		}
+		a.count++
+		l.counts[key] = a
+	}
+	return true
+}
+
+// Reset forgets the attempts of the keys, as after a successful one.
+func (l *AttemptLimiter) Reset(keys ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, key := range keys {
+		delete(l.counts, key)
+	}
+}
@@ -1,12 +1,19 @@
 package auth
 
 import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
+	"github.com/pquerna/otp/totp"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -36,7 +43,7 @@ func TestCheckPasswordHash(t *testing.T) {
 }
 
 func TestTokenBasic(t *testing.T) {
-	tokenSecret := "lollmao"
+	tokenSecret := NewHMACKeyRing("lollmao")
 	userID := uuid.New()
 
 	token, token_err := MakeJWT(userID, tokenSecret)
@@ -46,8 +53,18 @@ func TestTokenBasic(t *testing.T) {
 	}
 }
 
+func TestTokenExpiry(t *testing.T) {
+	keys := NewHMACKeyRing("lollmao")
+	issued := time.Now().Truncate(time.Second)
+	token, _ := MakeJWT(uuid.New(), keys)
+	_, expiresAt, err := ValidateJWTExpiry(token, keys)
+	if err != nil || expiresAt.Before(issued.Add(time.Hour)) || expiresAt.After(time.Now().Add(time.Hour)) {
+		t.Fatalf("TestTokenExpiry failed: %s, expires at %s", err, expiresAt)
+	}
+}
+
 func TestExpiredToken(t *testing.T) {
-	tokenSecret := "lollmao"
+	tokenSecret := NewHMACKeyRing("lollmao")
 	userID := uuid.New()
 	expiresAt := time.Now().Add(time.Hour)
 
@@ -61,16 +78,74 @@ func TestExpiredToken(t *testing.T) {
 }
 
 func TestWrongSecret(t *testing.T) {
-	tokenSecret := "lollmao"
+	tokenSecret := NewHMACKeyRing("lollmao")
 	userID := uuid.New()
 
 	token, token_err := MakeJWT(userID, tokenSecret)
-	tokenID, validation_err := ValidateJWT(token, "lol")
+	tokenID, validation_err := ValidateJWT(token, NewHMACKeyRing("lol"))
 	if validation_err == nil || token_err != nil || tokenID != uuid.Nil {
 		t.Fatalf("TestWrongSecret failed.\nToken error: %snValidation error: %s\nuserID: %s\ntokenID:%s", token_err, validation_err, userID, tokenID)
 	}
 }
 
+func TestKeyRotation(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := NewKeyRing()
+	if err := keys.AddKey("2024-01", rsaKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := keys.AddKey("2024-06", edKey); err != nil {
+		t.Fatal(err)
+	}
+	keys.SetActive("2024-01")
+	userID := uuid.New()
+	oldToken, _ := MakeJWT(userID, keys)
+	keys.SetActive("2024-06")
+	newToken, _ := MakeJWT(userID, keys)
+
+	for _, token := range []string{oldToken, newToken} {
+		if tokenID, err := ValidateJWT(token, keys); err != nil || tokenID != userID {
+			t.Fatalf("TestKeyRotation failed: %s", err)
+		}
+	}
+	verifier := NewKeyRing()
+	verifier.AddKey("2024-06", edKey.Public())
+	if _, err := ValidateJWT(oldToken, verifier); err == nil {
+		t.Fatalf("TestKeyRotation accepted a token signed by an unknown key")
+	}
+	if _, err := ValidateJWT(newToken, verifier); err != nil {
+		t.Fatalf("TestKeyRotation failed with public key only: %s", err)
+	}
+	jwks := keys.JWKS()
+	if len(jwks.Keys) != 2 || jwks.Keys[0].Alg != "RS256" || jwks.Keys[1].Crv != "Ed25519" {
+		t.Fatalf("TestKeyRotation unexpected jwks: %+v", jwks)
+	}
+}
+
+func TestLegacyTokenWithoutKid(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	userID := uuid.New()
+	legacyToken, _ := MakeJWT(userID, NewHMACKeyRing("lollmao"))
+
+	keys := NewKeyRing()
+	keys.AddKey("2024-06", edKey)
+	keys.SetActive("2024-06")
+	if _, err := ValidateJWT(legacyToken, keys); err == nil {
+		t.Fatalf("TestLegacyTokenWithoutKid accepted a token without a legacy secret")
+	}
+	keys.SetLegacySecret("lollmao")
+	if tokenID, err := ValidateJWT(legacyToken, keys); err != nil || tokenID != userID {
+		t.Fatalf("TestLegacyTokenWithoutKid failed: %s", err)
+	}
+}
+
 func TestGetBearer(t *testing.T) {
 	headers := make(http.Header)
 	empty_res, empty_err := GetBearerToken(headers)
@@ -87,3 +162,89 @@ func TestMakeRefreshToken(t *testing.T) {
 		t.Fatalf("TestMakeRefreshToken failed: %s", token)
 	}
 }
+
+func TestMFATokenIsNotAnAccessToken(t *testing.T) {
+	keys := NewHMACKeyRing("lollmao")
+	userID := uuid.New()
+	mfaToken, _ := MakeMFAToken(userID, keys)
+	accessToken, _ := MakeJWT(userID, keys)
+	if _, err := ValidateJWT(mfaToken, keys); err == nil {
+		t.Fatalf("TestMFATokenIsNotAnAccessToken accepted an MFA token as access token")
+	}
+	if _, err := ValidateMFAToken(accessToken, keys); err == nil {
+		t.Fatalf("TestMFATokenIsNotAnAccessToken accepted an access token as MFA token")
+	}
+	if tokenID, err := ValidateMFAToken(mfaToken, keys); err != nil || tokenID != userID {
+		t.Fatalf("TestMFATokenIsNotAnAccessToken failed: %s", err)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, uri, err := GenerateTOTPSecret("saul@bettercall.com")
+	if err != nil || !strings.HasPrefix(uri, "otpauth://totp/Chirpy:") {
+		t.Fatalf("TestValidateTOTP failed to generate a secret: %s %s", err, uri)
+	}
+	now := time.Unix(1700000000, 0)
+	code, _ := totp.GenerateCodeCustom(secret, now, totpOpts)
+	counter, ok := ValidateTOTP(secret, code, now.Add(25*time.Second))
+	if !ok || counter != now.Unix()/totpPeriod {
+		t.Fatalf("TestValidateTOTP rejected a code from the previous period")
+	}
+	if _, ok := ValidateTOTP(secret, code, now.Add(5*time.Minute)); ok {
+		t.Fatalf("TestValidateTOTP accepted a stale code")
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(10)
+	if err != nil || len(codes) != 10 || len(codes[0]) != 11 {
+		t.Fatalf("TestRecoveryCodes failed: %v %s", codes, err)
+	}
+	if HashToken(NormalizeRecoveryCode(strings.ToUpper(codes[0]))) != HashToken(strings.ReplaceAll(codes[0], "-", "")) {
+		t.Fatalf("TestRecoveryCodes normalization failed")
+	}
+}
+
+func TestAttemptLimiter(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	limiter := NewAttemptLimiter(5, 15*time.Minute)
+	limiter.now = func() time.Time { return now }
+	for range 5 {
+		if !limiter.Reserve("token", "user") {
+			t.Fatalf("TestAttemptLimiter locked out before 5 attempts")
+		}
+	}
+	if limiter.Reserve("token") || limiter.Reserve("other token", "user") {
+		t.Fatalf("TestAttemptLimiter allowed a key with 5 attempts")
+	}
+	if !limiter.Reserve("other token") {
+		t.Fatalf("TestAttemptLimiter locked out an unrelated key")
+	}
+	now = now.Add(15 * time.Minute)
+	if !limiter.Reserve("token", "user") {
+		t.Fatalf("TestAttemptLimiter still locked after the window")
+	}
+	limiter.Reset("token", "user")
+	if len(limiter.counts) != 0 {
+		t.Fatalf("TestAttemptLimiter kept expired or reset entries: %v", limiter.counts)
+	}
+}
+
+func TestAttemptLimiterConcurrent(t *testing.T) {
+	limiter := NewAttemptLimiter(5, 15*time.Minute)
+	var wg sync.WaitGroup
+	var allowed atomic.Int32
+	for range 50 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if limiter.Reserve("token", "user") {
+				allowed.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := allowed.Load(); n != 5 {
+		t.Fatalf("TestAttemptLimiterConcurrent allowed %d of 50 parallel guesses, want 5", n)
+	}
+}
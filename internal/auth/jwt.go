@@ -1,33 +1,85 @@
 package auth
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt"
 	"github.com/google/uuid"
 )
 
-func MakeJWT(userID uuid.UUID, tokenSecret string) (string, error) {
-	return jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.StandardClaims{
-		Issuer:    "chirpy",
-		IssuedAt:  time.Now().Unix(),
-		ExpiresAt: time.Now().Add(time.Hour).Unix(),
-		Subject:   userID.String(),
-	}).SignedString([]byte(tokenSecret))
+const (
+	accessAudience = "chirpy-access"
+	mfaAudience    = "chirpy-mfa"
+	mfaLifetime    = 5 * time.Minute
+)
+
+func MakeJWT(userID uuid.UUID, keys *KeyRing) (string, error) {
+	return makeToken(userID, accessAudience, time.Hour, keys)
 }
 
-func ValidateJWT(tokenString, tokenSecret string) (uuid.UUID, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.StandardClaims{}, func(t *jwt.Token) (interface{}, error) {
-		return []byte(tokenSecret), nil
-	})
+func ValidateJWT(tokenString string, keys *KeyRing) (uuid.UUID, error) {
+	userID, _, err := ValidateJWTExpiry(tokenString, keys)
+	return userID, err
+}
 
+// ValidateJWTExpiry also returns when the access token expires, for connections that
+// outlive a single request.
+func ValidateJWTExpiry(tokenString string, keys *KeyRing) (uuid.UUID, time.Time, error) {
+	claims, err := validateToken(tokenString, keys)
 	if err != nil {
-		return uuid.Nil, err
+		return uuid.Nil, time.Time{}, err
+	}
+	// access tokens issued before audiences were introduced have none
+	if claims.Audience != accessAudience && claims.Audience != "" {
+		return uuid.Nil, time.Time{}, fmt.Errorf("not an access token")
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, time.Time{}, err
 	}
+	return userID, time.Unix(claims.ExpiresAt, 0), nil
+}
+
+// MakeMFAToken issues the short-lived challenge token handed out after a correct password
+// when the account still needs a second factor. It is not accepted as an access token.
+func MakeMFAToken(userID uuid.UUID, keys *KeyRing) (string, error) {
+	return makeToken(userID, mfaAudience, mfaLifetime, keys)
+}
 
-	if claims, ok := token.Claims.(*jwt.StandardClaims); ok && token.Valid {
-		return uuid.MustParse(claims.Subject), nil
-	} else {
+func ValidateMFAToken(tokenString string, keys *KeyRing) (uuid.UUID, error) {
+	claims, err := validateToken(tokenString, keys)
+	if err != nil {
 		return uuid.Nil, err
 	}
+	if claims.Audience != mfaAudience {
+		return uuid.Nil, fmt.Errorf("not an MFA challenge token")
+	}
+	return uuid.Parse(claims.Subject)
+}
+
+func makeToken(userID uuid.UUID, audience string, lifetime time.Duration, keys *KeyRing) (string, error) {
+	return keys.sign(jwt.StandardClaims{
+		Issuer:    "chirpy",
+		Audience:  audience,
+		IssuedAt:  time.Now().Unix(),
+		ExpiresAt: time.Now().Add(lifetime).Unix(),
+		Subject:   userID.String(),
+	})
+}
+
+func validateToken(tokenString string, keys *KeyRing) (*jwt.StandardClaims, error) {
+	claims := jwt.StandardClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, &claims, keys.verificationKey)
+	if err != nil {
+		return nil, err
+	}
+	if !token.Valid {
+		return nil, fmt.Errorf("invalid token")
+	}
+	// jwt accepts a token in the very second it expires; RFC 7519 says it must be rejected
+	if claims.ExpiresAt <= jwt.TimeFunc().Unix() {
+		return nil, fmt.Errorf("token is expired")
+	}
+	return &claims, nil
 }
@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt"
+)
+
+const minRSABits = 2048
+
+// KeyRing holds the keys used to sign and verify access tokens.
+// Tokens are signed with the active key and carry its id in the "kid" header.
+// Retired keys stay in the ring (optionally as public keys only) so tokens they
+// signed keep validating until they expire.
+type KeyRing struct {
+	activeID string
+	keys     map[string]*jwtKey
+	// HS256 secret for tokens without a kid; never published in the JWKS
+	legacySecret []byte
+}
+
+type jwtKey struct {
+	method  jwt.SigningMethod
+	private crypto.PrivateKey // nil for verify-only keys
+	public  crypto.PublicKey
+}
+
+// JWK is the public half of a signing key as published in the JWKS document (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// Ed25519
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewKeyRing() *KeyRing {
+	return &KeyRing{keys: map[string]*jwtKey{}}
+}
+
+// NewHMACKeyRing signs and verifies with a single shared HS256 secret, as Chirpy did
+// before asymmetric keys were supported.
+func NewHMACKeyRing(secret string) *KeyRing {
+	keys := NewKeyRing()
+	keys.SetLegacySecret(secret)
+	return keys
+}
+
+// SetLegacySecret keeps accepting HS256 tokens without a kid, so switching to
+// asymmetric keys does not log everyone out.
+func (k *KeyRing) SetLegacySecret(secret string) {
+	if secret == "" {
+		k.legacySecret = nil
+		return
+	}
+	k.legacySecret = []byte(secret)
+}
+
+// AddKey adds an RSA (RS256) or Ed25519 (EdDSA) key under the given id.
+// Private keys can sign and verify; public keys can only verify.
+func (k *KeyRing) AddKey(id string, key any) error {
+	if id == "" {
+		return fmt.Errorf("key id must not be empty")
+	}
+	if _, ok := k.keys[id]; ok {
+		return fmt.Errorf("duplicate key id %q", id)
+	}
+	var entry jwtKey
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		entry = jwtKey{method: jwt.SigningMethodRS256, private: key, public: &key.PublicKey}
+	case *rsa.PublicKey:
+		entry = jwtKey{method: jwt.SigningMethodRS256, public: key}
+	case ed25519.PrivateKey:
+		entry = jwtKey{method: jwt.SigningMethodEdDSA, private: key, public: key.Public()}
+	case ed25519.PublicKey:
+		entry = jwtKey{method: jwt.SigningMethodEdDSA, public: key}
+	default:
+		return fmt.Errorf("key %q: unsupported key type %T", id, key)
+	}
+	if rsaKey, ok := entry.public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < minRSABits {
+		return fmt.Errorf("key %q: RSA keys must be at least %d bits", id, minRSABits)
+	}
+	k.keys[id] = &entry
+	return nil
+}
+
+// SetActive selects the key new tokens are signed with.
+func (k *KeyRing) SetActive(id string) error {
+	key, ok := k.keys[id]
+	if !ok {
+		return fmt.Errorf("unknown key id %q", id)
+	}
+	if key.private == nil {
+		return fmt.Errorf("key %q has no private key and cannot sign", id)
+	}
+	k.activeID = id
+	return nil
+}
+
+// LoadKeyRing reads every *.pem file in dir, using the file name (without extension) as the key id.
+// Files may hold a PKCS#8 or PKCS#1 private key, or a PKIX public key for retired keys.
+// If activeID is empty the last private key by file name becomes the active one,
+// so date-named files (2024-11.pem, 2025-02.pem, ...) rotate by adding a file.
+func LoadKeyRing(dir, activeID string) (*KeyRing, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+	keys := NewKeyRing()
+	for _, path := range paths {
+		id := strings.TrimSuffix(filepath.Base(path), ".pem")
+		dat, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		key, err := parsePEMKey(dat)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %w", id, err)
+		}
+		if err := keys.AddKey(id, key); err != nil {
+			return nil, err
+		}
+		if activeID == "" && keys.keys[id].private != nil {
+			keys.activeID = id
+		}
+	}
+	if activeID != "" {
+		if err := keys.SetActive(activeID); err != nil {
+			return nil, err
+		}
+	}
+	if keys.activeID == "" {
+		return nil, fmt.Errorf("no private signing key found in %s", dir)
+	}
+	return keys, nil
+}
+
+func parsePEMKey(dat []byte) (any, error) {
+	block, _ := pem.Decode(dat)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data found")
+	}
+	switch block.Type {
+	case "PRIVATE KEY":
+		return x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		return x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+}
+
+// JWKS returns the public keys of the ring, sorted by id. The legacy HMAC secret is never included.
+func (k *KeyRing) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	ids := make([]string, 0, len(k.keys))
+	for id := range k.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		key := k.keys[id]
+		jwk := JWK{Kid: id, Use: "sig", Alg: key.method.Alg()}
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+func (k *KeyRing) sign(claims jwt.Claims) (string, error) {
+	if k.activeID == "" {
+		if k.legacySecret == nil {
+			return "", fmt.Errorf("no signing key configured")
+		}
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(k.legacySecret)
+	}
+	key := k.keys[k.activeID]
+	token := jwt.NewWithClaims(key.method, claims)
+	token.Header["kid"] = k.activeID
+	return token.SignedString(key.private)
+}
+
+// verificationKey is the jwt.Keyfunc for the ring. It insists on the algorithm
+// registered for the key so a public key can never be used as an HMAC secret.
+func (k *KeyRing) verificationKey(token *jwt.Token) (interface{}, error) {
+	kid, hasKid := token.Header["kid"].(string)
+	if !hasKid {
+		if k.legacySecret == nil || token.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("token has no key id")
+		}
+		return k.legacySecret, nil
+	}
+	key, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %s for key %q", token.Method.Alg(), kid)
+	}
+	return key.public, nil
+}
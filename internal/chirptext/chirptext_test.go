@@ -0,0 +1,66 @@
+package chirptext
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestExtractHashtags(t *testing.T) {
+	cases := map[string][]string{
+		"no tags here":                        nil,
+		"#golang is fun":                      {"golang"},
+		"I love #Go and #go and #GO":          {"go"},
+		"#one, #two. (#three)":                {"one", "two", "three"},
+		"issue #1 is not a tag but #v2 is":    {"v2"},
+		"email me@#host and a&#39; or url/#x": nil,
+		"unicode #Café works":                 {"café"},
+	}
+	for body, want := range cases {
+		if got := ExtractHashtags(body); !slices.Equal(got, want) {
+			t.Errorf("ExtractHashtags(%q) = %v, want %v", body, got, want)
+		}
+	}
+}
+
+func TestExtractMentions(t *testing.T) {
+	cases := map[string][]Mention{
+		"no mentions":                 nil,
+		"@Alice hi":                   {{Username: "alice", Start: 0, End: 6}},
+		"hey @bob_99, and @carol!":    {{Username: "bob_99", Start: 4, End: 11}, {Username: "carol", Start: 17, End: 23}},
+		"mail bob@example.com or @ab": nil,
+		"café @dave":                  {{Username: "dave", Start: 5, End: 10}},
+	}
+	for body, want := range cases {
+		if got := ExtractMentions(body); !slices.Equal(got, want) {
+			t.Errorf("ExtractMentions(%q) = %v, want %v", body, got, want)
+		}
+	}
+}
+
+func TestValidUsername(t *testing.T) {
+	for name, want := range map[string]bool{
+		"bob":                             true,
+		"Bob_2020":                        true,
+		"ab":                              false,
+		"bob smith":                       false,
+		"bøb":                             false,
+		"abcdefghijabcdefghijabcdefghijk": false,
+	} {
+		if got := ValidUsername(name); got != want {
+			t.Errorf("ValidUsername(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	cases := map[string]string{
+		"plain \x02match\x03 text":                        "plain <mark>match</mark> text",
+		"<mark>fake</mark> <img src=x onerror=\x02a\x03>": "&lt;mark&gt;fake&lt;/mark&gt; &lt;img src=x onerror=<mark>a</mark>&gt;",
+		"\"quoted\" & \x02amp\x03":                        "&#34;quoted&#34; &amp; <mark>amp</mark>",
+	}
+	for headline, want := range cases {
+		if got := Highlight(headline); got != want {
+			t.Errorf("Highlight(%q) = %q, want %q", headline, got, want)
+		}
+	}
+}
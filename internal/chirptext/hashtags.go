@@ -0,0 +1,33 @@
+package chirptext
+
+import (
+	"regexp"
+	"strings"
+)
+
+const maxHashtagLength = 64
+
+// a hashtag starts at the beginning of the body or after a character that can't be part of a word,
+// and must contain at least one letter so that "#1" is not a tag
+var hashtagRegex = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&#/@])#([\p{L}\p{N}_]*\p{L}[\p{L}\p{N}_]*)`)
+
+// ExtractHashtags returns the distinct hashtags in body, lowercased and without the leading '#',
+// in order of first appearance.
+func ExtractHashtags(body string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, match := range hashtagRegex.FindAllStringSubmatch(body, -1) {
+		tag := NormalizeHashtag(match[1])
+		if len([]rune(tag)) > maxHashtagLength || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// NormalizeHashtag brings a tag from user input into the form it is stored in.
+func NormalizeHashtag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(tag, "#"))
+}
@@ -0,0 +1,21 @@
+package chirptext
+
+import (
+	"html"
+	"strings"
+)
+
+// HighlightStart and HighlightStop delimit matches in search headlines. The search queries
+// remove both characters from chirp bodies before highlighting, so the only ones in a headline
+// are the markers themselves.
+const (
+	HighlightStart = "\x02"
+	HighlightStop  = "\x03"
+)
+
+var highlightReplacer = strings.NewReplacer(HighlightStart, "<mark>", HighlightStop, "</mark>")
+
+// Highlight HTML-escapes a search headline and wraps its marked matches in <mark></mark>.
+func Highlight(headline string) string {
+	return highlightReplacer.Replace(html.EscapeString(headline))
+}
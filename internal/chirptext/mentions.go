@@ -0,0 +1,55 @@
+package chirptext
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	minUsernameLength = 3
+	maxUsernameLength = 30
+)
+
+var usernameRegex = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// mentions may not follow a word character, '@' or '.', which rules out email addresses
+var mentionRegex = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_@.])(@[A-Za-z0-9_]+)`)
+
+// Mention is an @username in a chirp body. Start and End are offsets in Unicode code points,
+// covering the '@' and the username, with End exclusive.
+type Mention struct {
+	Username string
+	Start    int
+	End      int
+}
+
+// ValidUsername reports whether name can be used as a handle.
+func ValidUsername(name string) bool {
+	return len(name) >= minUsernameLength && len(name) <= maxUsernameLength && usernameRegex.MatchString(name)
+}
+
+// NormalizeUsername returns the case-insensitive form that usernames are compared in.
+func NormalizeUsername(name string) string {
+	return strings.ToLower(strings.TrimPrefix(name, "@"))
+}
+
+// ExtractMentions returns every @mention of a syntactically valid username in body, in order.
+// Usernames are normalized; nothing is known about whether the users exist.
+func ExtractMentions(body string) []Mention {
+	var mentions []Mention
+	for _, match := range mentionRegex.FindAllStringSubmatchIndex(body, -1) {
+		start, end := match[2], match[3]
+		name := body[start+1 : end]
+		if !ValidUsername(name) {
+			continue
+		}
+		runeStart := utf8.RuneCountInString(body[:start])
+		mentions = append(mentions, Mention{
+			Username: NormalizeUsername(name),
+			Start:    runeStart,
+			End:      runeStart + utf8.RuneCountInString(body[start:end]),
+		})
+	}
+	return mentions
+}
@@ -7,29 +7,106 @@ package database
 
 import (
 	"context"
+	"database/sql"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 )
 
 const createChirp = `-- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, reply_to)
 VALUES (
     gen_random_uuid(),
 	NOW(),
 	NOW(),
 	$1,
-	$2
+	$2,
+	$3
 )
-RETURNING id, created_at, updated_at, body, user_id
+RETURNING id, created_at, updated_at, body, user_id, reply_to, rechirp_of, quote_of, is_quote
 `
 
 type CreateChirpParams struct {
-	Body   string
-	UserID uuid.UUID
+	Body    string
+	UserID  uuid.UUID
+	ReplyTo uuid.NullUUID
 }
 
 func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp, error) {
-	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID)
+	row := q.db.QueryRowContext(ctx, createChirp, arg.Body, arg.UserID, arg.ReplyTo)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyTo,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.IsQuote,
+	)
+	return i, err
+}
+
+const createQuoteChirp = `-- name: CreateQuoteChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, quote_of, is_quote)
+VALUES (
+    gen_random_uuid(),
+	NOW(),
+	NOW(),
+	$1,
+	$2,
+	$3,
+	true
+)
+RETURNING id, created_at, updated_at, body, user_id, reply_to, rechirp_of, quote_of, is_quote
+`
+
+type CreateQuoteChirpParams struct {
+	Body    string
+	UserID  uuid.UUID
+	QuoteOf uuid.NullUUID
+}
+
+func (q *Queries) CreateQuoteChirp(ctx context.Context, arg CreateQuoteChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createQuoteChirp, arg.Body, arg.UserID, arg.QuoteOf)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyTo,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.IsQuote,
+	)
+	return i, err
+}
+
+const createRechirp = `-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of)
+VALUES (
+    gen_random_uuid(),
+	NOW(),
+	NOW(),
+	'',
+	$1,
+	$2
+)
+ON CONFLICT (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL DO NOTHING
+RETURNING id, created_at, updated_at, body, user_id, reply_to, rechirp_of, quote_of, is_quote
+`
+
+type CreateRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) CreateRechirp(ctx context.Context, arg CreateRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, createRechirp, arg.UserID, arg.RechirpOf)
 	var i Chirp
 	err := row.Scan(
 		&i.ID,
@@ -37,6 +114,10 @@ func (q *Queries) CreateChirp(ctx context.Context, arg CreateChirpParams) (Chirp
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ReplyTo,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.IsQuote,
 	)
 	return i, err
 }
@@ -51,13 +132,19 @@ func (q *Queries) DeleteChirp(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
-const getAllChirpsAsc = `-- name: GetAllChirpsAsc :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-ORDER BY created_at ASC
+const deleteChirpsOfPurgedUsers = `-- name: DeleteChirpsOfPurgedUsers :many
+DELETE FROM chirps
+WHERE user_id IN (SELECT id FROM users WHERE deleted_at < $1)
+OR rechirp_of IN (
+	SELECT chirps.id FROM chirps
+	JOIN users ON users.id = chirps.user_id
+	WHERE users.deleted_at < $1
+)
+RETURNING id, created_at, updated_at, body, user_id, reply_to, rechirp_of, quote_of, is_quote
 `
 
-func (q *Queries) GetAllChirpsAsc(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirpsAsc)
+func (q *Queries) DeleteChirpsOfPurgedUsers(ctx context.Context, deletedAt sql.NullTime) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, deleteChirpsOfPurgedUsers, deletedAt)
 	if err != nil {
 		return nil, err
 	}
@@ -71,6 +158,10 @@ func (q *Queries) GetAllChirpsAsc(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.IsQuote,
 		); err != nil {
 			return nil, err
 		}
@@ -85,13 +176,42 @@ func (q *Queries) GetAllChirpsAsc(ctx context.Context) ([]Chirp, error) {
 	return items, nil
 }
 
-const getAllChirpsDesc = `-- name: GetAllChirpsDesc :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-ORDER BY created_at DESC
+const deleteRechirp = `-- name: DeleteRechirp :one
+DELETE FROM chirps
+WHERE user_id = $1 AND rechirp_of = $2
+RETURNING id, created_at, updated_at, body, user_id, reply_to, rechirp_of, quote_of, is_quote
+`
+
+type DeleteRechirpParams struct {
+	UserID    uuid.UUID
+	RechirpOf uuid.NullUUID
+}
+
+func (q *Queries) DeleteRechirp(ctx context.Context, arg DeleteRechirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, deleteRechirp, arg.UserID, arg.RechirpOf)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyTo,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.IsQuote,
+	)
+	return i, err
+}
+
+const deleteRechirpsOf = `-- name: DeleteRechirpsOf :many
+DELETE FROM chirps
+WHERE rechirp_of = $1
+RETURNING id, created_at, updated_at, body, user_id, reply_to, rechirp_of, quote_of, is_quote
 `
 
-func (q *Queries) GetAllChirpsDesc(ctx context.Context) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getAllChirpsDesc)
+func (q *Queries) DeleteRechirpsOf(ctx context.Context, rechirpOf uuid.NullUUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, deleteRechirpsOf, rechirpOf)
 	if err != nil {
 		return nil, err
 	}
@@ -105,6 +225,10 @@ func (q *Queries) GetAllChirpsDesc(ctx context.Context) ([]Chirp, error) {
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.IsQuote,
 		); err != nil {
 			return nil, err
 		}
@@ -120,7 +244,7 @@ func (q *Queries) GetAllChirpsDesc(ctx context.Context) ([]Chirp, error) {
 }
 
 const getChirp = `-- name: GetChirp :one
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+SELECT id, created_at, updated_at, body, user_id, reply_to, rechirp_of, quote_of, is_quote FROM chirps
 WHERE id = $1
 `
 
@@ -133,18 +257,435 @@ func (q *Queries) GetChirp(ctx context.Context, id uuid.UUID) (Chirp, error) {
 		&i.UpdatedAt,
 		&i.Body,
 		&i.UserID,
+		&i.ReplyTo,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.IsQuote,
 	)
 	return i, err
 }
 
-const getUserChirpsAsc = `-- name: GetUserChirpsAsc :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
-WHERE user_id = $1
-ORDER BY created_at ASC
+const getChirpAncestors = `-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors(id, reply_to, depth) AS (
+	SELECT chirps.id, chirps.reply_to, 0 FROM chirps
+	WHERE chirps.id = $1::uuid
+	UNION ALL
+	SELECT parent.id, parent.reply_to, ancestors.depth + 1 FROM chirps parent
+	JOIN ancestors ON parent.id = ancestors.reply_to
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.reply_to, chirps.rechirp_of, chirps.quote_of, chirps.is_quote FROM chirps
+JOIN ancestors ON chirps.id = ancestors.id
+WHERE ancestors.depth > 0
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = $2::uuid AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = $2::uuid AND muted_id = chirps.user_id
+)
+ORDER BY ancestors.depth DESC
 `
 
-func (q *Queries) GetUserChirpsAsc(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getUserChirpsAsc, userID)
+type GetChirpAncestorsParams struct {
+	ChirpID  uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) GetChirpAncestors(ctx context.Context, arg GetChirpAncestorsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpAncestors, arg.ChirpID, arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.IsQuote,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpDescendants = `-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants(id) AS (
+	SELECT chirps.id FROM chirps
+	WHERE chirps.reply_to = $1::uuid
+	UNION ALL
+	SELECT child.id FROM chirps child
+	JOIN descendants ON child.reply_to = descendants.id
+)
+SELECT chirps.id, chirps.created_at, chirps.updated_at, chirps.body, chirps.user_id, chirps.reply_to, chirps.rechirp_of, chirps.quote_of, chirps.is_quote FROM chirps
+JOIN descendants ON chirps.id = descendants.id
+WHERE NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = $2::uuid AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = $2::uuid AND muted_id = chirps.user_id
+)
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT $3
+`
+
+type GetChirpDescendantsParams struct {
+	ChirpID    uuid.UUID
+	ViewerID   uuid.NullUUID
+	MaxResults int32
+}
+
+func (q *Queries) GetChirpDescendants(ctx context.Context, arg GetChirpDescendantsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpDescendants, arg.ChirpID, arg.ViewerID, arg.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.IsQuote,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpForUpdate = `-- name: GetChirpForUpdate :one
+SELECT id, created_at, updated_at, body, user_id, reply_to, rechirp_of, quote_of, is_quote FROM chirps
+WHERE id = $1
+FOR UPDATE
+`
+
+func (q *Queries) GetChirpForUpdate(ctx context.Context, id uuid.UUID) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getChirpForUpdate, id)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyTo,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.IsQuote,
+	)
+	return i, err
+}
+
+const getChirpsByIDs = `-- name: GetChirpsByIDs :many
+SELECT id, created_at, updated_at, body, user_id, reply_to, rechirp_of, quote_of, is_quote FROM chirps
+WHERE id = ANY($1::uuid[])
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = $2::uuid AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = $2::uuid AND muted_id = chirps.user_id
+)
+`
+
+type GetChirpsByIDsParams struct {
+	ChirpIds []uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) GetChirpsByIDs(ctx context.Context, arg GetChirpsByIDsParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsByIDs, pq.Array(arg.ChirpIds), arg.ViewerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.IsQuote,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsPageAsc = `-- name: GetChirpsPageAsc :many
+SELECT id, created_at, updated_at, body, user_id, reply_to, rechirp_of, quote_of, is_quote FROM chirps
+WHERE ($1::uuid IS NULL OR user_id = $1)
+AND ($2::timestamp IS NULL
+	OR (created_at, id) > ($2, $3::uuid))
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = $4::uuid AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = $4::uuid AND muted_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+ORDER BY created_at ASC, id ASC
+LIMIT $5
+`
+
+type GetChirpsPageAscParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	ViewerID        uuid.NullUUID
+	PageLimit       int32
+}
+
+func (q *Queries) GetChirpsPageAsc(ctx context.Context, arg GetChirpsPageAscParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsPageAsc,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.ViewerID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.IsQuote,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getChirpsPageDesc = `-- name: GetChirpsPageDesc :many
+SELECT id, created_at, updated_at, body, user_id, reply_to, rechirp_of, quote_of, is_quote FROM chirps
+WHERE ($1::uuid IS NULL OR user_id = $1)
+AND ($2::timestamp IS NULL
+	OR (created_at, id) < ($2, $3::uuid))
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = $4::uuid AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = $4::uuid AND muted_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+ORDER BY created_at DESC, id DESC
+LIMIT $5
+`
+
+type GetChirpsPageDescParams struct {
+	AuthorID        uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	ViewerID        uuid.NullUUID
+	PageLimit       int32
+}
+
+func (q *Queries) GetChirpsPageDesc(ctx context.Context, arg GetChirpsPageDescParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getChirpsPageDesc,
+		arg.AuthorID,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.ViewerID,
+		arg.PageLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []Chirp
+	for rows.Next() {
+		var i Chirp
+		if err := rows.Scan(
+			&i.ID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.Body,
+			&i.UserID,
+			&i.ReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.IsQuote,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRechirpCounts = `-- name: GetRechirpCounts :many
+SELECT rechirp_of, COUNT(*) AS rechirp_count FROM chirps
+WHERE rechirp_of = ANY($1::uuid[])
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+GROUP BY rechirp_of
+`
+
+type GetRechirpCountsRow struct {
+	RechirpOf    uuid.NullUUID
+	RechirpCount int64
+}
+
+func (q *Queries) GetRechirpCounts(ctx context.Context, chirpIds []uuid.UUID) ([]GetRechirpCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getRechirpCounts, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetRechirpCountsRow
+	for rows.Next() {
+		var i GetRechirpCountsRow
+		if err := rows.Scan(
+			&i.RechirpOf,
+			&i.RechirpCount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getRepliesPage = `-- name: GetRepliesPage :many
+SELECT id, created_at, updated_at, body, user_id, reply_to, rechirp_of, quote_of, is_quote FROM chirps
+WHERE reply_to = $1
+AND ($2::timestamp IS NULL
+	OR (created_at, id) > ($2, $3::uuid))
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = $4::uuid AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = $4::uuid AND muted_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+ORDER BY created_at ASC, id ASC
+LIMIT $5
+`
+
+type GetRepliesPageParams struct {
+	ReplyTo         uuid.NullUUID
+	CursorCreatedAt sql.NullTime
+	CursorID        uuid.NullUUID
+	ViewerID        uuid.NullUUID
+	PageLimit       int32
+}
+
+func (q *Queries) GetRepliesPage(ctx context.Context, arg GetRepliesPageParams) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getRepliesPage,
+		arg.ReplyTo,
+		arg.CursorCreatedAt,
+		arg.CursorID,
+		arg.ViewerID,
+		arg.PageLimit,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -158,6 +699,51 @@ func (q *Queries) GetUserChirpsAsc(ctx context.Context, userID uuid.UUID) ([]Chi
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.IsQuote,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const getReplyCounts = `-- name: GetReplyCounts :many
+SELECT reply_to, COUNT(*) AS reply_count FROM chirps
+WHERE reply_to = ANY($1::uuid[])
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+GROUP BY reply_to
+`
+
+type GetReplyCountsRow struct {
+	ReplyTo    uuid.NullUUID
+	ReplyCount int64
+}
+
+func (q *Queries) GetReplyCounts(ctx context.Context, chirpIds []uuid.UUID) ([]GetReplyCountsRow, error) {
+	rows, err := q.db.QueryContext(ctx, getReplyCounts, pq.Array(chirpIds))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []GetReplyCountsRow
+	for rows.Next() {
+		var i GetReplyCountsRow
+		if err := rows.Scan(
+			&i.ReplyTo,
+			&i.ReplyCount,
 		); err != nil {
 			return nil, err
 		}
@@ -172,14 +758,14 @@ func (q *Queries) GetUserChirpsAsc(ctx context.Context, userID uuid.UUID) ([]Chi
 	return items, nil
 }
 
-const getUserChirpsDesc = `-- name: GetUserChirpsDesc :many
-SELECT id, created_at, updated_at, body, user_id FROM chirps
+const getUserChirpsAsc = `-- name: GetUserChirpsAsc :many
+SELECT id, created_at, updated_at, body, user_id, reply_to, rechirp_of, quote_of, is_quote FROM chirps
 WHERE user_id = $1
-ORDER BY created_at DESC
+ORDER BY created_at ASC
 `
 
-func (q *Queries) GetUserChirpsDesc(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
-	rows, err := q.db.QueryContext(ctx, getUserChirpsDesc, userID)
+func (q *Queries) GetUserChirpsAsc(ctx context.Context, userID uuid.UUID) ([]Chirp, error) {
+	rows, err := q.db.QueryContext(ctx, getUserChirpsAsc, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -193,6 +779,10 @@ func (q *Queries) GetUserChirpsDesc(ctx context.Context, userID uuid.UUID) ([]Ch
 			&i.UpdatedAt,
 			&i.Body,
 			&i.UserID,
+			&i.ReplyTo,
+			&i.RechirpOf,
+			&i.QuoteOf,
+			&i.IsQuote,
 		); err != nil {
 			return nil, err
 		}
@@ -206,3 +796,72 @@ func (q *Queries) GetUserChirpsDesc(ctx context.Context, userID uuid.UUID) ([]Ch
 	}
 	return items, nil
 }
+
+const getVisibleChirp = `-- name: GetVisibleChirp :one
+SELECT id, created_at, updated_at, body, user_id, reply_to, rechirp_of, quote_of, is_quote FROM chirps
+WHERE id = $1
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = $2::uuid AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = $2::uuid AND muted_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+`
+
+type GetVisibleChirpParams struct {
+	ID       uuid.UUID
+	ViewerID uuid.NullUUID
+}
+
+func (q *Queries) GetVisibleChirp(ctx context.Context, arg GetVisibleChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, getVisibleChirp, arg.ID, arg.ViewerID)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyTo,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.IsQuote,
+	)
+	return i, err
+}
+
+const updateChirp = `-- name: UpdateChirp :one
+UPDATE chirps
+SET body = $2,
+	updated_at = NOW()
+WHERE id = $1
+RETURNING id, created_at, updated_at, body, user_id, reply_to, rechirp_of, quote_of, is_quote
+`
+
+type UpdateChirpParams struct {
+	ID   uuid.UUID
+	Body string
+}
+
+func (q *Queries) UpdateChirp(ctx context.Context, arg UpdateChirpParams) (Chirp, error) {
+	row := q.db.QueryRowContext(ctx, updateChirp, arg.ID, arg.Body)
+	var i Chirp
+	err := row.Scan(
+		&i.ID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.Body,
+		&i.UserID,
+		&i.ReplyTo,
+		&i.RechirpOf,
+		&i.QuoteOf,
+		&i.IsQuote,
+	)
+	return i, err
+}
@@ -0,0 +1,41 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestFileMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer, err := NewFileMailer(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := Message{To: "saul@bettercall.com", Subject: "Reset your password", Body: "line one\nline two"}
+	if err := mailer.Send(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Fatalf("expected one message file, got %d", len(entries))
+	}
+	dat, _ := os.ReadFile(dir + "/" + entries[0].Name())
+	if !strings.Contains(string(dat), "To: saul@bettercall.com\r\n") || !strings.HasSuffix(string(dat), "line one\r\nline two") {
+		t.Fatalf("unexpected message:\n%s", dat)
+	}
+}
+
+func TestHeaderInjection(t *testing.T) {
+	var buf bytes.Buffer
+	mailer := NewLogMailer(&buf)
+	msg := Message{To: "saul@bettercall.com\r\nBcc: everyone@example.com", Subject: "hi", Body: "hi"}
+	if err := mailer.Send(context.Background(), msg); err == nil {
+		t.Fatalf("accepted a recipient containing a line break")
+	}
+	if buf.Len() != 0 {
+		t.Fatalf("wrote a rejected message")
+	}
+}
@@ -0,0 +1,121 @@
+package moderation
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Action string
+
+const (
+	ActionMask   Action = "mask"
+	ActionReject Action = "reject"
+	ActionFlag   Action = "flag"
+)
+
+const mask = "****"
+
+func (a Action) Valid() bool {
+	return a == ActionMask || a == ActionReject || a == ActionFlag
+}
+
+type Term struct {
+	Term   string
+	Action Action
+}
+
+// Result describes what the filter did to a body. Rejected and Flagged list the matched terms.
+type Result struct {
+	Body     string
+	Rejected []string
+	Flagged  []string
+}
+
+type rule struct {
+	term Term
+	re   *regexp.Regexp
+}
+
+// Filter matches moderation terms as whole words, case-insensitively. A nil *Filter matches nothing.
+type Filter struct {
+	rules []rule
+}
+
+// NormalizeTerm returns the form terms are stored and compared in.
+func NormalizeTerm(term string) string {
+	return strings.ToLower(strings.TrimSpace(term))
+}
+
+func NewFilter(terms []Term) (*Filter, error) {
+	filter := &Filter{}
+	for _, term := range terms {
+		if !term.Action.Valid() {
+			return nil, fmt.Errorf("invalid action %q for term %q", term.Action, term.Term)
+		}
+		normalized := NormalizeTerm(term.Term)
+		if normalized == "" {
+			continue
+		}
+		filter.rules = append(filter.rules, rule{
+			term: Term{Term: normalized, Action: term.Action},
+			re:   regexp.MustCompile(`(?i)` + regexp.QuoteMeta(normalized)),
+		})
+	}
+	return filter, nil
+}
+
+func (f *Filter) Apply(body string) Result {
+	result := Result{Body: body}
+	if f == nil {
+		return result
+	}
+	for _, rule := range f.rules {
+		matches := wholeWordMatches(rule.re, result.Body)
+		if len(matches) == 0 {
+			continue
+		}
+		switch rule.term.Action {
+		case ActionMask:
+			result.Body = maskMatches(result.Body, matches)
+		case ActionReject:
+			result.Rejected = append(result.Rejected, rule.term.Term)
+		case ActionFlag:
+			result.Flagged = append(result.Flagged, rule.term.Term)
+		}
+	}
+	return result
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsNumber(r) || unicode.IsMark(r) || r == '_'
+}
+
+// wholeWordMatches keeps the matches of re that are not directly preceded or followed by a word character.
+func wholeWordMatches(re *regexp.Regexp, body string) [][]int {
+	var matches [][]int
+	for _, match := range re.FindAllStringIndex(body, -1) {
+		if before, _ := utf8.DecodeLastRuneInString(body[:match[0]]); before != utf8.RuneError && isWordRune(before) {
+			continue
+		}
+		if after, _ := utf8.DecodeRuneInString(body[match[1]:]); after != utf8.RuneError && isWordRune(after) {
+			continue
+		}
+		matches = append(matches, match)
+	}
+	return matches
+}
+
+func maskMatches(body string, matches [][]int) string {
+	var masked strings.Builder
+	last := 0
+	for _, match := range matches {
+		masked.WriteString(body[last:match[0]])
+		masked.WriteString(mask)
+		last = match[1]
+	}
+	masked.WriteString(body[last:])
+	return masked.String()
+}
@@ -0,0 +1,51 @@
+package moderation
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFilterMask(t *testing.T) {
+	filter, err := NewFilter([]Term{{Term: "kerfuffle", Action: ActionMask}, {Term: "Fornax", Action: ActionMask}})
+	if err != nil {
+		t.Fatalf("NewFilter failed: %s", err)
+	}
+	cases := map[string]string{
+		"What a KERFUFFLE!":          "What a ****!",
+		"kerfuffles are fine":        "kerfuffles are fine",
+		"fornax kerfuffle fornax":    "**** **** ****",
+		"übkerfuffle and kerfuffleé": "übkerfuffle and kerfuffleé",
+	}
+	for body, want := range cases {
+		if got := filter.Apply(body).Body; got != want {
+			t.Errorf("Apply(%q).Body = %q, want %q", body, got, want)
+		}
+	}
+}
+
+func TestFilterRejectAndFlag(t *testing.T) {
+	filter, err := NewFilter([]Term{{Term: "sharbert", Action: ActionReject}, {Term: "bad word", Action: ActionFlag}})
+	if err != nil {
+		t.Fatalf("NewFilter failed: %s", err)
+	}
+	result := filter.Apply("a Sharbert said a bad  word, then a BAD WORD")
+	if !slices.Equal(result.Rejected, []string{"sharbert"}) || !slices.Equal(result.Flagged, []string{"bad word"}) {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if result.Body != "a Sharbert said a bad  word, then a BAD WORD" {
+		t.Fatalf("reject and flag must not change the body: %q", result.Body)
+	}
+}
+
+func TestNilFilter(t *testing.T) {
+	var filter *Filter
+	if result := filter.Apply("kerfuffle"); result.Body != "kerfuffle" || result.Rejected != nil || result.Flagged != nil {
+		t.Fatalf("nil filter changed the body: %+v", result)
+	}
+}
+
+func TestInvalidAction(t *testing.T) {
+	if _, err := NewFilter([]Term{{Term: "x", Action: "delete"}}); err == nil {
+		t.Fatalf("NewFilter accepted an invalid action")
+	}
+}
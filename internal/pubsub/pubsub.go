@@ -0,0 +1,133 @@
+// Package pubsub is an in-process publish/subscribe broker for real-time updates.
+//
+// Every event gets an increasing ID and the most recent events are kept in memory,
+// so a subscriber that reconnects can resume after the last event it saw.
+package pubsub
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before it is dropped.
+const subscriberBuffer = 64
+
+type Event struct {
+	ID    uint64
+	Topic string
+	Type  string
+	// the user the event is about: a chirp's author, a notification's recipient
+	UserID uuid.UUID
+	// JSON-encoded payload
+	Data []byte
+}
+
+// Broker fans events out to subscribers. Publishing never blocks: a subscriber whose buffer
+// is full is dropped and has to resubscribe, so one slow client cannot hold up the others.
+type Broker struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []Event // ring buffer of the most recent events
+	next        int     // slot in history the next event is written to
+	subscribers map[*Subscription]struct{}
+}
+
+type Subscription struct {
+	broker *Broker
+	filter func(Event) bool
+	events chan Event
+	closed bool
+}
+
+// NewBroker keeps the last historySize events for resuming subscribers.
+func NewBroker(historySize int) *Broker {
+	return &Broker{
+		// IDs start from the clock rather than zero, so an ID handed out before a restart
+		// is older than anything in the new history instead of colliding with it
+		lastID:      uint64(time.Now().UnixNano()),
+		history:     make([]Event, 0, historySize),
+		subscribers: map[*Subscription]struct{}{},
+	}
+}
+
+// Publish assigns the event its ID and delivers it to every matching subscriber.
+func (b *Broker) Publish(event Event) Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastID++
+	event.ID = b.lastID
+	if len(b.history) < cap(b.history) {
+		b.history = append(b.history, event)
+	} else if len(b.history) > 0 {
+		b.history[b.next] = event
+		b.next = (b.next + 1) % len(b.history)
+	}
+	for sub := range b.subscribers {
+		if !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			b.remove(sub)
+		}
+	}
+	return event
+}
+
+// Subscribe registers a subscriber for events matching filter. If afterID is not zero, the
+// retained events after it are returned for replay; complete is false when some events after
+// afterID are no longer retained, in which case the subscriber should reload its state.
+func (b *Broker) Subscribe(afterID uint64, filter func(Event) bool) (sub *Subscription, replay []Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	sub = &Subscription{
+		broker: b,
+		filter: filter,
+		events: make(chan Event, subscriberBuffer),
+	}
+	b.subscribers[sub] = struct{}{}
+	if afterID == 0 {
+		return sub, nil, true
+	}
+	ordered := append(append([]Event{}, b.history[b.next:]...), b.history[:b.next]...)
+	switch {
+	case afterID > b.lastID:
+		// an ID this broker never handed out
+		complete = false
+	case afterID == b.lastID:
+		complete = true
+	default:
+		// IDs are consecutive, so nothing is missing if the oldest retained event directly follows afterID
+		complete = len(ordered) > 0 && ordered[0].ID <= afterID+1
+	}
+	for _, event := range ordered {
+		if event.ID > afterID && filter(event) {
+			replay = append(replay, event)
+		}
+	}
+	return sub, replay, complete
+}
+
+// Events delivers matching events. It is closed when the subscription is closed, including
+// when the broker drops a subscriber that fell too far behind.
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscription) Close() {
+	s.broker.mu.Lock()
+	defer s.broker.mu.Unlock()
+	s.broker.remove(s)
+}
+
+func (b *Broker) remove(sub *Subscription) {
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(b.subscribers, sub)
+	close(sub.events)
+}
@@ -0,0 +1,81 @@
+package pubsub
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func all(Event) bool { return true }
+
+func TestPublishFiltersSubscribers(t *testing.T) {
+	broker := NewBroker(10)
+	author := uuid.New()
+	sub, _, _ := broker.Subscribe(0, func(e Event) bool { return e.UserID == author })
+	defer sub.Close()
+	broker.Publish(Event{Topic: "chirps", UserID: uuid.New()})
+	published := broker.Publish(Event{Topic: "chirps", UserID: author})
+	select {
+	case got := <-sub.Events():
+		if got.ID != published.ID {
+			t.Fatalf("got event %d, want %d", got.ID, published.ID)
+		}
+	default:
+		t.Fatal("no event delivered")
+	}
+	select {
+	case got := <-sub.Events():
+		t.Fatalf("unexpected event %d", got.ID)
+	default:
+	}
+}
+
+func TestResume(t *testing.T) {
+	broker := NewBroker(3)
+	var ids []uint64
+	for range 5 {
+		ids = append(ids, broker.Publish(Event{Topic: "chirps"}).ID)
+	}
+
+	sub, replay, complete := broker.Subscribe(ids[2], all)
+	sub.Close()
+	if !complete || len(replay) != 2 || replay[0].ID != ids[3] || replay[1].ID != ids[4] {
+		t.Fatalf("resume after %d: got %v complete=%v", ids[2], replay, complete)
+	}
+
+	sub, replay, complete = broker.Subscribe(ids[4], all)
+	sub.Close()
+	if !complete || len(replay) != 0 {
+		t.Fatalf("resume at head: got %v complete=%v", replay, complete)
+	}
+
+	// ids[1] has been evicted from the history, so ids[1]+1 cannot be replayed
+	sub, replay, complete = broker.Subscribe(ids[0], all)
+	sub.Close()
+	if complete || len(replay) != 3 {
+		t.Fatalf("resume after evicted event: got %v complete=%v", replay, complete)
+	}
+
+	// an ID from before a restart
+	sub, _, complete = NewBroker(3).Subscribe(ids[4], all)
+	sub.Close()
+	if complete {
+		t.Fatal("resuming from an unknown ID reported complete")
+	}
+}
+
+func TestSlowSubscriberIsDropped(t *testing.T) {
+	broker := NewBroker(0)
+	sub, _, _ := broker.Subscribe(0, all)
+	for range subscriberBuffer + 1 {
+		broker.Publish(Event{Topic: "chirps"})
+	}
+	received := 0
+	for range sub.Events() {
+		received++
+	}
+	if received != subscriberBuffer {
+		t.Fatalf("received %d events before being dropped, want %d", received, subscriberBuffer)
+	}
+	sub.Close()
+}
@@ -0,0 +1,79 @@
+// Package storage keeps user uploads such as avatars.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Storage stores blobs under flat keys and knows the public URL they are served from.
+type Storage interface {
+	Put(ctx context.Context, key string, content io.Reader) error
+	Delete(ctx context.Context, key string) error
+	URL(key string) string
+}
+
+// LocalDisk stores files in a directory that is served by the app's file server.
+type LocalDisk struct {
+	dir     string
+	baseURL string
+}
+
+// NewLocalDisk stores files in dir, which must be served at baseURL (e.g. "/app/assets/avatars").
+func NewLocalDisk(dir, baseURL string) (*LocalDisk, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &LocalDisk{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+func validKey(key string) error {
+	if key == "" || key == "." || key == ".." || strings.ContainsAny(key, `/\`) {
+		return fmt.Errorf("invalid storage key %q", key)
+	}
+	return nil
+}
+
+// Put writes to a temporary file first so readers never see a partial upload.
+func (s *LocalDisk) Put(ctx context.Context, key string, content io.Reader) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := io.Copy(tmp, content); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filepath.Join(s.dir, key))
+}
+
+// Delete removes the file; deleting a missing key is not an error.
+func (s *LocalDisk) Delete(ctx context.Context, key string) error {
+	if err := validKey(key); err != nil {
+		return err
+	}
+	err := os.Remove(filepath.Join(s.dir, key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *LocalDisk) URL(key string) string {
+	return s.baseURL + "/" + url.PathEscape(key)
+}
@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalDisk(t *testing.T) {
+	dir := t.TempDir()
+	disk, err := NewLocalDisk(dir, "/app/assets/avatars/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	if err := disk.Put(ctx, "avatar.png", strings.NewReader("png")); err != nil {
+		t.Fatal(err)
+	}
+	dat, err := os.ReadFile(filepath.Join(dir, "avatar.png"))
+	if err != nil || string(dat) != "png" {
+		t.Fatalf("unexpected file content %q: %s", dat, err)
+	}
+	if url := disk.URL("avatar.png"); url != "/app/assets/avatars/avatar.png" {
+		t.Fatalf("unexpected url %s", url)
+	}
+	if err := disk.Delete(ctx, "avatar.png"); err != nil {
+		t.Fatal(err)
+	}
+	if err := disk.Delete(ctx, "avatar.png"); err != nil {
+		t.Fatalf("deleting a missing key failed: %s", err)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 0 {
+		t.Fatalf("left files behind: %v", entries)
+	}
+}
+
+func TestLocalDiskRejectsPaths(t *testing.T) {
+	disk, _ := NewLocalDisk(t.TempDir(), "/app/assets/avatars")
+	for _, key := range []string{"", "..", "../escape.png", "nested/avatar.png"} {
+		if err := disk.Put(context.Background(), key, strings.NewReader("x")); err == nil {
+			t.Errorf("accepted key %q", key)
+		}
+	}
+}
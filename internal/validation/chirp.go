@@ -0,0 +1,30 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+const MaxChirpLength = 140
+
+// ChirpBody trims body and checks it against the chirp length rules. Length is counted in
+// grapheme clusters, so an emoji built from several code points counts as one character.
+func ChirpBody(body *string) (string, Errors) {
+	var errs Errors
+	if body == nil {
+		errs.Add(CodeRequired, "body", "missing required fields: body")
+		return "", errs
+	}
+	trimmed := strings.TrimSpace(*body)
+	if trimmed == "" {
+		errs.Add(CodeEmpty, "body", "empty chirp")
+		return "", errs
+	}
+	if length := uniseg.GraphemeClusterCount(trimmed); length > MaxChirpLength {
+		errs.Add(CodeTooLong, "body", fmt.Sprintf("overlong chirp: %d of at most %d characters", length, MaxChirpLength))
+		return "", errs
+	}
+	return trimmed, nil
+}
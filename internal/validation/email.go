@@ -0,0 +1,30 @@
+package validation
+
+import (
+	"net/mail"
+	"strings"
+)
+
+// MaxEmailLength is the longest address SMTP can deliver to (RFC 5321 path limit minus the brackets).
+const MaxEmailLength = 254
+
+// Email validates a bare address such as "saul@bettercall.com" and returns it trimmed.
+// Display names ("Saul <saul@bettercall.com>") and comments are rejected.
+func Email(email string) (string, Errors) {
+	var errs Errors
+	email = strings.TrimSpace(email)
+	if email == "" {
+		errs.Add(CodeRequired, "email", "missing required fields: email")
+		return "", errs
+	}
+	if len(email) > MaxEmailLength {
+		errs.Add(CodeTooLong, "email", "email address is too long")
+		return "", errs
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Name != "" || addr.Address != email {
+		errs.Add(CodeInvalid, "email", "invalid email address")
+		return "", errs
+	}
+	return email, nil
+}
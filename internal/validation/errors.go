@@ -0,0 +1,38 @@
+package validation
+
+import "strings"
+
+const (
+	CodeRequired          = "required"
+	CodeEmpty             = "empty"
+	CodeTooLong           = "too_long"
+	CodeInvalid           = "invalid"
+	CodeNotFound          = "not_found"
+	CodeProhibitedContent = "prohibited_content"
+)
+
+// FieldError describes why the value of one request field was rejected.
+type FieldError struct {
+	Code    string `json:"code"`
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Errors collects every problem found with a request so clients can show them all at once.
+type Errors []FieldError
+
+func (errs *Errors) Add(code, field, message string) {
+	*errs = append(*errs, FieldError{Code: code, Field: field, Message: message})
+}
+
+func (errs Errors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Message)
+	}
+	return strings.Join(messages, "; ")
+}
@@ -0,0 +1,33 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+const (
+	MaxMessageLength = 1000
+	// including the creator
+	MaxConversationParticipants = 10
+)
+
+// MessageBody trims a direct message and checks its length in grapheme clusters.
+func MessageBody(body *string) (string, Errors) {
+	var errs Errors
+	if body == nil {
+		errs.Add(CodeRequired, "body", "missing required fields: body")
+		return "", errs
+	}
+	trimmed := strings.TrimSpace(*body)
+	if trimmed == "" {
+		errs.Add(CodeEmpty, "body", "empty message")
+		return "", errs
+	}
+	if length := uniseg.GraphemeClusterCount(trimmed); length > MaxMessageLength {
+		errs.Add(CodeTooLong, "body", fmt.Sprintf("overlong message: %d of at most %d characters", length, MaxMessageLength))
+		return "", errs
+	}
+	return trimmed, nil
+}
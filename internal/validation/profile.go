@@ -0,0 +1,47 @@
+package validation
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+const (
+	MaxDisplayNameLength = 50
+	MaxBioLength         = 160
+	MaxLocationLength    = 30
+	MaxWebsiteLength     = 100
+)
+
+// ProfileText trims a free-text profile field and checks its length in grapheme clusters.
+// An empty value is valid and clears the field.
+func ProfileText(field, value string, maxLength int) (string, Errors) {
+	var errs Errors
+	value = strings.TrimSpace(value)
+	if uniseg.GraphemeClusterCount(value) > maxLength {
+		errs.Add(CodeTooLong, field, fmt.Sprintf("%s must be at most %d characters", field, maxLength))
+		return "", errs
+	}
+	return value, nil
+}
+
+// Website accepts an absolute http(s) URL, or an empty string to clear the field.
+func Website(website string) (string, Errors) {
+	var errs Errors
+	website = strings.TrimSpace(website)
+	if website == "" {
+		return "", nil
+	}
+	if len(website) > MaxWebsiteLength {
+		errs.Add(CodeTooLong, "website", fmt.Sprintf("website must be at most %d characters", MaxWebsiteLength))
+		return "", errs
+	}
+	parsed, err := url.Parse(website)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		errs.Add(CodeInvalid, "website", "website must be an http or https URL")
+		return "", errs
+	}
+	return website, nil
+}
@@ -0,0 +1,114 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChirpBody(t *testing.T) {
+	family := "👨‍👩‍👧‍👦"
+	cases := []struct {
+		name string
+		body *string
+		want string
+		code string
+	}{
+		{"missing", nil, "", CodeRequired},
+		{"blank", ptr("   \n"), "", CodeEmpty},
+		{"trimmed", ptr("  hello  "), "hello", ""},
+		{"at limit", ptr(strings.Repeat("a", MaxChirpLength)), strings.Repeat("a", MaxChirpLength), ""},
+		{"over limit", ptr(strings.Repeat("a", MaxChirpLength+1)), "", CodeTooLong},
+		{"emoji count as one", ptr(strings.Repeat(family, MaxChirpLength)), strings.Repeat(family, MaxChirpLength), ""},
+		{"emoji over limit", ptr(strings.Repeat(family, MaxChirpLength+1)), "", CodeTooLong},
+	}
+	for _, c := range cases {
+		got, errs := ChirpBody(c.body)
+		if got != c.want {
+			t.Errorf("%s: got body %q, want %q", c.name, got, c.want)
+		}
+		if c.code == "" && len(errs) != 0 {
+			t.Errorf("%s: unexpected errors %v", c.name, errs)
+		}
+		if c.code != "" && (len(errs) != 1 || errs[0].Code != c.code || errs[0].Field != "body") {
+			t.Errorf("%s: got errors %v, want one %s error on body", c.name, errs, c.code)
+		}
+	}
+}
+
+func TestEmail(t *testing.T) {
+	cases := []struct {
+		email string
+		want  string
+		code  string
+	}{
+		{"saul@bettercall.com", "saul@bettercall.com", ""},
+		{"  saul@bettercall.com ", "saul@bettercall.com", ""},
+		{"", "", CodeRequired},
+		{"saul", "", CodeInvalid},
+		{"saul@", "", CodeInvalid},
+		{"Saul <saul@bettercall.com>", "", CodeInvalid},
+		{"saul@bettercall.com, kim@bettercall.com", "", CodeInvalid},
+		{strings.Repeat("a", MaxEmailLength) + "@bettercall.com", "", CodeTooLong},
+	}
+	for _, c := range cases {
+		got, errs := Email(c.email)
+		if got != c.want {
+			t.Errorf("%q: got %q, want %q", c.email, got, c.want)
+		}
+		if c.code == "" && len(errs) != 0 {
+			t.Errorf("%q: unexpected errors %v", c.email, errs)
+		}
+		if c.code != "" && (len(errs) != 1 || errs[0].Code != c.code || errs[0].Field != "email") {
+			t.Errorf("%q: got errors %v, want one %s error on email", c.email, errs, c.code)
+		}
+	}
+}
+
+func TestProfileFields(t *testing.T) {
+	if got, errs := ProfileText("bio", "  S'all good, man.  ", MaxBioLength); got != "S'all good, man." || len(errs) != 0 {
+		t.Errorf("bio: got %q, %v", got, errs)
+	}
+	if _, errs := ProfileText("location", strings.Repeat("a", MaxLocationLength+1), MaxLocationLength); len(errs) != 1 || errs[0].Code != CodeTooLong {
+		t.Errorf("location: got %v, want too_long", errs)
+	}
+	for website, code := range map[string]string{
+		"":                            "",
+		"https://bettercallsaul.com":  "",
+		"javascript:alert(1)":         CodeInvalid,
+		"bettercallsaul.com":          CodeInvalid,
+		"ftp://bettercallsaul.com/cv": CodeInvalid,
+	} {
+		_, errs := Website(website)
+		if (code == "" && len(errs) != 0) || (code != "" && (len(errs) != 1 || errs[0].Code != code)) {
+			t.Errorf("website %q: got %v, want %q", website, errs, code)
+		}
+	}
+}
+
+func TestErrorsMessage(t *testing.T) {
+	var errs Errors
+	errs.Add(CodeEmpty, "body", "empty chirp")
+	errs.Add(CodeNotFound, "reply_to", "the chirp being replied to does not exist")
+	if errs.Error() != "empty chirp; the chirp being replied to does not exist" {
+		t.Fatalf("unexpected message: %s", errs.Error())
+	}
+}
+
+func ptr(s string) *string {
+	return &s
+}
+
+func TestMessageBody(t *testing.T) {
+	if got, errs := MessageBody(ptr("  Did you know you have rights?  ")); got != "Did you know you have rights?" || len(errs) != 0 {
+		t.Errorf("got %q, %v", got, errs)
+	}
+	if _, errs := MessageBody(ptr(strings.Repeat("a", MaxChirpLength+1))); len(errs) != 0 {
+		t.Errorf("messages may be longer than chirps, got %v", errs)
+	}
+	if _, errs := MessageBody(ptr(strings.Repeat("a", MaxMessageLength+1))); len(errs) != 1 || errs[0].Code != CodeTooLong {
+		t.Errorf("got %v, want too_long", errs)
+	}
+	if _, errs := MessageBody(ptr(" ")); len(errs) != 1 || errs[0].Code != CodeEmpty {
+		t.Errorf("got %v, want empty", errs)
+	}
+}
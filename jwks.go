@@ -0,0 +1,18 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// jwksHandler publishes the public access-token keys so other services can verify
+// Chirpy tokens without holding any signing secret.
+func (cfg *apiConfig) jwksHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		w.Header().Add("Cache-Control", "public, max-age=300")
+		dat, _ := json.Marshal(cfg.jwtKeys.JWKS())
+		w.WriteHeader(200)
+		w.Write(dat)
+	})
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type likedChirpResponse struct {
+	chirpResponse
+	LikedAt time.Time `json:"liked_at"`
+}
+
+type likedChirpPageResponse struct {
+	Chirps     []likedChirpResponse `json:"chirps"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+func (cfg *apiConfig) likeChirpHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+		if err != nil {
+			writeError(w, "invalid chirp id", 404)
+			return
+		}
+		chirp, err := cfg.db.GetVisibleChirp(r.Context(), database.GetVisibleChirpParams{ID: chirpID})
+		if err != nil {
+			writeError(w, "chirp not found", 404)
+			return
+		}
+		blocked, err := cfg.isBlockedBy(r.Context(), chirp.UserID, userID)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error checking blocks: %s", err), 500)
+			return
+		}
+		if blocked {
+			writeError(w, "you cannot like this user's chirps", 403)
+			return
+		}
+		var notifications []database.Notification
+		err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+			err := q.LikeChirp(r.Context(), database.LikeChirpParams{
+				UserID:  userID,
+				ChirpID: chirpID,
+			})
+			if err != nil {
+				return err
+			}
+			notifications, err = notify(r.Context(), q, chirp.UserID, userID, notificationLike, chirp.ID)
+			return err
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error liking chirp: %s", err), 400)
+			return
+		}
+		cfg.publishNotifications(notifications)
+		w.WriteHeader(204)
+	})
+}
+
+func (cfg *apiConfig) unlikeChirpHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+		if err != nil {
+			writeError(w, "invalid chirp id", 404)
+			return
+		}
+		err = cfg.db.UnlikeChirp(r.Context(), database.UnlikeChirpParams{
+			UserID:  userID,
+			ChirpID: chirpID,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error unliking chirp: %s", err), 400)
+			return
+		}
+		w.WriteHeader(204)
+	})
+}
+
+func (cfg *apiConfig) getUserLikesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := uuid.Parse(r.PathValue("userID"))
+		if err != nil {
+			writeError(w, "invalid user id", 404)
+			return
+		}
+		limit, cursor, err := parsePageParams(r)
+		if err != nil {
+			writeError(w, err.Error(), 400)
+			return
+		}
+		likes, err := cfg.db.GetUserLikesPage(r.Context(), database.GetUserLikesPageParams{
+			UserID:          userID,
+			CursorCreatedAt: cursor.CreatedAt,
+			CursorID:        cursor.ID,
+			PageLimit:       limit + 1,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving likes: %s", err), 400)
+			return
+		}
+		response := likedChirpPageResponse{Chirps: []likedChirpResponse{}}
+		if len(likes) > int(limit) {
+			likes = likes[:limit]
+			last := likes[len(likes)-1]
+			response.NextCursor = encodeCursor(last.LikedAt, last.Chirp.ID)
+		}
+		chirps := make([]database.Chirp, 0, len(likes))
+		for _, like := range likes {
+			chirps = append(chirps, like.Chirp)
+		}
+		responses, err := cfg.chirpResponses(r.Context(), cfg.viewerID(r), chirps)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving likes: %s", err), 400)
+			return
+		}
+		for i, like := range likes {
+			response.Chirps = append(response.Chirps, likedChirpResponse{
+				chirpResponse: responses[i],
+				LikedAt:       like.LikedAt,
+			})
+		}
+		dat, _ := json.Marshal(response)
+		w.Write(dat)
+	})
+}
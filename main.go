@@ -1,13 +1,21 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
 	"sync/atomic"
+	"time"
 
+	"github.com/Kurlgargyey/chirpy/internal/auth"
 	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/Kurlgargyey/chirpy/internal/mail"
+	"github.com/Kurlgargyey/chirpy/internal/moderation"
+	"github.com/Kurlgargyey/chirpy/internal/pubsub"
+	"github.com/Kurlgargyey/chirpy/internal/storage"
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
@@ -15,9 +23,19 @@ import (
 type apiConfig struct {
 	fileserverHits atomic.Int32
 	db             *database.Queries
+	conn           *sql.DB
 	platform       string
-	jwtSecret      string
+	jwtKeys        *auth.KeyRing
 	polkaKey       string
+	adminKey       string
+	// compiled from the moderation_terms table, see reloadModerationFilter
+	moderationFilter atomic.Pointer[moderation.Filter]
+	mailer           mail.Mailer
+	// when set, users must verify their email address before posting chirps
+	requireVerifiedEmail bool
+	avatars              storage.Storage
+	events               *pubsub.Broker
+	mfaAttempts          *auth.AttemptLimiter
 }
 
 func main() {
@@ -28,13 +46,52 @@ func main() {
 		fmt.Println("error connecting to database: %w", err)
 		return
 	}
+	jwtKeys := auth.NewHMACKeyRing(os.Getenv("SECRET"))
+	if keysDir := os.Getenv("JWT_KEYS_DIR"); keysDir != "" {
+		jwtKeys, err = auth.LoadKeyRing(keysDir, os.Getenv("JWT_SIGNING_KEY_ID"))
+		if err != nil {
+			fmt.Println("error loading jwt keys:", err)
+			return
+		}
+		// tokens signed with SECRET before the switch stay valid until they expire
+		jwtKeys.SetLegacySecret(os.Getenv("SECRET"))
+	}
+	// mail goes to SMTP_ADDR if set, else to files in MAIL_DIR, else to stdout
+	var mailer mail.Mailer = mail.NewLogMailer(os.Stdout)
+	if smtpAddr := os.Getenv("SMTP_ADDR"); smtpAddr != "" {
+		mailer, err = mail.NewSMTPMailer(smtpAddr, os.Getenv("SMTP_USERNAME"), os.Getenv("SMTP_PASSWORD"), os.Getenv("MAIL_FROM"))
+	} else if mailDir := os.Getenv("MAIL_DIR"); mailDir != "" {
+		mailer, err = mail.NewFileMailer(mailDir)
+	}
+	if err != nil {
+		fmt.Println("error configuring mail:", err)
+		return
+	}
+	// uploads live under the file server root so they are served from /app/
+	avatars, err := storage.NewLocalDisk(filepath.Join("assets", "avatars"), "/app/assets/avatars")
+	if err != nil {
+		fmt.Println("error configuring avatar storage:", err)
+		return
+	}
 	apiCfg := apiConfig{
-		fileserverHits: atomic.Int32{},
-		db:             database.New(db),
-		platform:       os.Getenv("PLATFORM"),
-		jwtSecret:      os.Getenv("SECRET"),
-		polkaKey:       os.Getenv("POLKA_KEY"),
+		fileserverHits:       atomic.Int32{},
+		db:                   database.New(db),
+		conn:                 db,
+		platform:             os.Getenv("PLATFORM"),
+		jwtKeys:              jwtKeys,
+		polkaKey:             os.Getenv("POLKA_KEY"),
+		adminKey:             os.Getenv("ADMIN_KEY"),
+		mailer:               mailer,
+		requireVerifiedEmail: os.Getenv("REQUIRE_VERIFIED_EMAIL") == "true",
+		avatars:              avatars,
+		events:               pubsub.NewBroker(eventHistorySize),
+		mfaAttempts:          auth.NewAttemptLimiter(maxMFAAttempts, mfaLockout),
+	}
+	if err := apiCfg.reloadModerationFilter(context.Background()); err != nil {
+		fmt.Println("error loading moderation terms:", err)
+		return
 	}
+	go apiCfg.runAccountPurger(context.Background(), time.Hour)
 	srvMux := http.NewServeMux()
 	fileServer := http.StripPrefix("/app",
 		http.FileServer(http.Dir(".")))
@@ -47,19 +104,76 @@ func main() {
 			response.Header().Add("Content-Type", "text/plain; charset=utf-8")
 			response.Write([]byte("OK"))
 		})
+	srvMux.Handle("GET /.well-known/jwks.json", apiCfg.jwksHandler())
 	srvMux.Handle("GET /admin/metrics", apiCfg.metricsHandler())
 	srvMux.Handle("POST /admin/reset", apiCfg.resetHandler())
-	srvMux.Handle("POST /api/validate_chirp", validateChirpHandler())
+	srvMux.Handle("POST /api/validate_chirp", apiCfg.validateChirpHandler())
 	srvMux.Handle("POST /api/users", apiCfg.createUserHandler())
 	srvMux.Handle("POST /api/login", apiCfg.loginHandler())
+	srvMux.Handle("POST /api/login/mfa", apiCfg.mfaLoginHandler())
 	srvMux.Handle("POST /api/chirps", apiCfg.createChirpHandler())
 	srvMux.Handle("GET /api/chirps", apiCfg.getChirpsHandler())
 	srvMux.Handle("GET /api/chirps/{chirpID}", apiCfg.getChirpHandler())
+	srvMux.Handle("POST /api/password/forgot", apiCfg.forgotPasswordHandler())
+	srvMux.Handle("POST /api/password/reset", apiCfg.resetPasswordHandler())
+	srvMux.Handle("POST /api/users/verify", apiCfg.verifyEmailHandler())
+	srvMux.Handle("POST /api/users/me/verification", apiCfg.resendVerificationHandler())
 	srvMux.Handle("POST /api/refresh", apiCfg.refreshTokenHandler())
 	srvMux.Handle("POST /api/revoke", apiCfg.revokeTokenHandler())
 	srvMux.Handle("PUT /api/users", apiCfg.updateUserHandler())
+	srvMux.Handle("PATCH /api/users/me", apiCfg.patchUserHandler())
+	srvMux.Handle("DELETE /api/users/me", apiCfg.deleteAccountHandler())
+	srvMux.Handle("GET /api/users/me/export", apiCfg.exportAccountHandler())
+	srvMux.Handle("PUT /api/users/me/avatar", apiCfg.uploadAvatarHandler())
+	srvMux.Handle("DELETE /api/users/me/avatar", apiCfg.deleteAvatarHandler())
+	srvMux.Handle("GET /api/users/{userID}", apiCfg.getUserProfileHandler())
 	srvMux.Handle("DELETE /api/chirps/{chirpID}", apiCfg.deleteChirpHandler())
+	srvMux.Handle("PATCH /api/chirps/{chirpID}", apiCfg.editChirpHandler())
+	srvMux.Handle("GET /api/chirps/{chirpID}/history", apiCfg.getChirpHistoryHandler())
+	srvMux.Handle("GET /api/chirps/{chirpID}/replies", apiCfg.getRepliesHandler())
+	srvMux.Handle("GET /api/chirps/{chirpID}/thread", apiCfg.getThreadHandler())
 	srvMux.Handle("POST /api/polka/webhooks", apiCfg.upgradeUserHandler())
+	srvMux.Handle("POST /api/users/{userID}/follow", apiCfg.followUserHandler())
+	srvMux.Handle("DELETE /api/users/{userID}/follow", apiCfg.unfollowUserHandler())
+	srvMux.Handle("POST /api/users/{userID}/block", apiCfg.blockUserHandler())
+	srvMux.Handle("DELETE /api/users/{userID}/block", apiCfg.unblockUserHandler())
+	srvMux.Handle("POST /api/users/{userID}/mute", apiCfg.muteUserHandler())
+	srvMux.Handle("DELETE /api/users/{userID}/mute", apiCfg.unmuteUserHandler())
+	srvMux.Handle("POST /api/conversations", apiCfg.createConversationHandler())
+	srvMux.Handle("GET /api/conversations", apiCfg.getConversationsHandler())
+	srvMux.Handle("GET /api/conversations/{conversationID}/messages", apiCfg.getMessagesHandler())
+	srvMux.Handle("POST /api/conversations/{conversationID}/messages", apiCfg.sendMessageHandler())
+	srvMux.Handle("POST /api/conversations/{conversationID}/read", apiCfg.markConversationReadHandler())
+	srvMux.Handle("GET /api/stream/chirps", apiCfg.streamChirpsHandler())
+	srvMux.Handle("GET /api/ws", apiCfg.websocketHandler())
+	srvMux.Handle("GET /api/notifications", apiCfg.getNotificationsHandler())
+	srvMux.Handle("GET /api/notifications/unread_count", apiCfg.unreadNotificationCountHandler())
+	srvMux.Handle("POST /api/notifications/read", apiCfg.markAllNotificationsReadHandler())
+	srvMux.Handle("POST /api/notifications/{notificationID}/read", apiCfg.markNotificationReadHandler())
+	srvMux.Handle("GET /api/users/{userID}/followers", apiCfg.getFollowersHandler())
+	srvMux.Handle("GET /api/users/{userID}/following", apiCfg.getFollowingHandler())
+	srvMux.Handle("GET /api/timeline", apiCfg.timelineHandler())
+	srvMux.Handle("POST /api/chirps/{chirpID}/likes", apiCfg.likeChirpHandler())
+	srvMux.Handle("DELETE /api/chirps/{chirpID}/likes", apiCfg.unlikeChirpHandler())
+	srvMux.Handle("GET /api/users/{userID}/likes", apiCfg.getUserLikesHandler())
+	srvMux.Handle("POST /api/chirps/{chirpID}/rechirps", apiCfg.rechirpHandler())
+	srvMux.Handle("DELETE /api/chirps/{chirpID}/rechirps", apiCfg.undoRechirpHandler())
+	srvMux.Handle("POST /api/chirps/{chirpID}/quotes", apiCfg.quoteChirpHandler())
+	srvMux.Handle("GET /api/hashtags/trending", apiCfg.trendingHashtagsHandler())
+	srvMux.Handle("GET /api/hashtags/{tag}/chirps", apiCfg.getHashtagChirpsHandler())
+	srvMux.Handle("POST /api/users/me/totp", apiCfg.enrollTOTPHandler())
+	srvMux.Handle("POST /api/users/me/totp/confirm", apiCfg.confirmTOTPHandler())
+	srvMux.Handle("DELETE /api/users/me/totp", apiCfg.disableTOTPHandler())
+	srvMux.Handle("GET /api/users/me/sessions", apiCfg.getSessionsHandler())
+	srvMux.Handle("DELETE /api/users/me/sessions", apiCfg.revokeAllSessionsHandler())
+	srvMux.Handle("DELETE /api/users/me/sessions/{sessionID}", apiCfg.revokeSessionHandler())
+	srvMux.Handle("GET /api/users/me/mentions", apiCfg.getMyMentionsHandler())
+	srvMux.Handle("GET /api/search/chirps", apiCfg.searchChirpsHandler())
+	srvMux.Handle("GET /admin/moderation/terms", apiCfg.listModerationTermsHandler())
+	srvMux.Handle("POST /admin/moderation/terms", apiCfg.upsertModerationTermHandler())
+	srvMux.Handle("DELETE /admin/moderation/terms/{termID}", apiCfg.deleteModerationTermHandler())
+	srvMux.Handle("GET /admin/moderation/flags", apiCfg.listFlaggedChirpsHandler())
+	srvMux.Handle("DELETE /admin/moderation/flags/{chirpID}", apiCfg.dismissChirpFlagsHandler())
 
 	//run server
 	server := http.Server{
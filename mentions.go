@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Kurlgargyey/chirpy/internal/chirptext"
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// mentionResponse locates a mention in the chirp body; offsets count Unicode code points and End is exclusive.
+type mentionResponse struct {
+	UserID uuid.UUID `json:"user_id"`
+	Start  int32     `json:"start"`
+	End    int32     `json:"end"`
+}
+
+// indexMentions replaces the mentions recorded for the chirp with the @usernames in its body
+// that belong to existing users.
+func indexMentions(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	if err := q.ClearChirpMentions(ctx, chirp.ID); err != nil {
+		return err
+	}
+	mentions := chirptext.ExtractMentions(chirp.Body)
+	if len(mentions) == 0 {
+		return nil
+	}
+	usernames := make([]string, 0, len(mentions))
+	for _, mention := range mentions {
+		usernames = append(usernames, mention.Username)
+	}
+	users, err := q.GetUsersByUsernames(ctx, usernames)
+	if err != nil {
+		return err
+	}
+	userIDs := make(map[string]uuid.UUID, len(users))
+	for _, user := range users {
+		userIDs[chirptext.NormalizeUsername(user.Username.String)] = user.ID
+	}
+	for _, mention := range mentions {
+		userID, ok := userIDs[mention.Username]
+		if !ok {
+			continue
+		}
+		err := q.CreateChirpMention(ctx, database.CreateChirpMentionParams{
+			ChirpID:     chirp.ID,
+			UserID:      userID,
+			StartOffset: int32(mention.Start),
+			EndOffset:   int32(mention.End),
+			CreatedAt:   chirp.CreatedAt,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) getMyMentionsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		limit, cursor, err := parsePageParams(r)
+		if err != nil {
+			writeError(w, err.Error(), 400)
+			return
+		}
+		chirps, err := cfg.db.GetMentionsPage(r.Context(), database.GetMentionsPageParams{
+			UserID:          userID,
+			CursorCreatedAt: cursor.CreatedAt,
+			CursorID:        cursor.ID,
+			PageLimit:       limit + 1,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving mentions: %s", err), 400)
+			return
+		}
+		cfg.writeChirpPage(w, r, chirps, limit)
+	})
+}
@@ -0,0 +1,265 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kurlgargyey/chirpy/internal/auth"
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/Kurlgargyey/chirpy/internal/moderation"
+	"github.com/google/uuid"
+)
+
+const defaultFlaggedChirpsLimit = 50
+
+type moderationTermRequestBody struct {
+	Term   string `json:"term"`
+	Action string `json:"action"`
+}
+
+type moderationTermResponse struct {
+	ID        uuid.UUID `json:"id"`
+	Term      string    `json:"term"`
+	Action    string    `json:"action"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type flaggedChirpResponse struct {
+	chirpResponse
+	Terms     []string  `json:"terms"`
+	FlaggedAt time.Time `json:"flagged_at"`
+}
+
+// reloadModerationFilter recompiles the cached filter from the moderation_terms table.
+func (cfg *apiConfig) reloadModerationFilter(ctx context.Context) error {
+	rows, err := cfg.db.GetModerationTerms(ctx)
+	if err != nil {
+		return err
+	}
+	terms := make([]moderation.Term, 0, len(rows))
+	for _, row := range rows {
+		terms = append(terms, moderation.Term{Term: row.Term, Action: moderation.Action(row.Action)})
+	}
+	filter, err := moderation.NewFilter(terms)
+	if err != nil {
+		return err
+	}
+	cfg.moderationFilter.Store(filter)
+	return nil
+}
+
+// moderateChirpBody masks the body's masked terms and rejects it if it contains a rejected one.
+// Flagged terms are left in place and recorded by indexChirp once the chirp is stored.
+func (cfg *apiConfig) moderateChirpBody(body string) (string, error) {
+	result := cfg.moderationFilter.Load().Apply(body)
+	if len(result.Rejected) > 0 {
+		return "", fmt.Errorf("chirp contains prohibited terms: %s", strings.Join(result.Rejected, ", "))
+	}
+	return result.Body, nil
+}
+
+// flagChirp replaces the moderation flags of the chirp with the flagged terms in its body.
+func (cfg *apiConfig) flagChirp(ctx context.Context, q *database.Queries, chirp database.Chirp) error {
+	if _, err := q.ClearChirpFlags(ctx, chirp.ID); err != nil {
+		return err
+	}
+	for _, term := range cfg.moderationFilter.Load().Apply(chirp.Body).Flagged {
+		err := q.FlagChirp(ctx, database.FlagChirpParams{
+			ChirpID: chirp.ID,
+			Term:    term,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (cfg *apiConfig) isAdmin(r *http.Request) bool {
+	apiKey, err := auth.GetAPIKey(r.Header)
+	if err != nil || cfg.adminKey == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(apiKey), []byte(cfg.adminKey)) == 1
+}
+
+func (cfg *apiConfig) listModerationTermsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		if !cfg.isAdmin(r) {
+			w.WriteHeader(401)
+			return
+		}
+		terms, err := cfg.db.GetModerationTerms(r.Context())
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving moderation terms: %s", err), 400)
+			return
+		}
+		response := []moderationTermResponse{}
+		for _, term := range terms {
+			response = append(response, moderationTermResponse{
+				ID:        term.ID,
+				Term:      term.Term,
+				Action:    term.Action,
+				CreatedAt: term.CreatedAt,
+				UpdatedAt: term.UpdatedAt,
+			})
+		}
+		dat, _ := json.Marshal(response)
+		w.Write(dat)
+	})
+}
+
+func (cfg *apiConfig) upsertModerationTermHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		if !cfg.isAdmin(r) {
+			w.WriteHeader(401)
+			return
+		}
+		var requestBody moderationTermRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			writeError(w, fmt.Sprintf("error decoding json: %s", err), 400)
+			return
+		}
+		term := moderation.NormalizeTerm(requestBody.Term)
+		if term == "" {
+			writeError(w, "missing required fields: term", 400)
+			return
+		}
+		if !moderation.Action(requestBody.Action).Valid() {
+			writeError(w, "action must be one of mask, reject or flag", 400)
+			return
+		}
+		saved, err := cfg.db.UpsertModerationTerm(r.Context(), database.UpsertModerationTermParams{
+			Term:   term,
+			Action: requestBody.Action,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error saving moderation term: %s", err), 400)
+			return
+		}
+		if err := cfg.reloadModerationFilter(r.Context()); err != nil {
+			writeError(w, fmt.Sprintf("error reloading moderation filter: %s", err), 500)
+			return
+		}
+		dat, _ := json.Marshal(moderationTermResponse{
+			ID:        saved.ID,
+			Term:      saved.Term,
+			Action:    saved.Action,
+			CreatedAt: saved.CreatedAt,
+			UpdatedAt: saved.UpdatedAt,
+		})
+		w.Write(dat)
+	})
+}
+
+func (cfg *apiConfig) deleteModerationTermHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		if !cfg.isAdmin(r) {
+			w.WriteHeader(401)
+			return
+		}
+		termID, err := uuid.Parse(r.PathValue("termID"))
+		if err != nil {
+			writeError(w, "invalid term id", 404)
+			return
+		}
+		deleted, err := cfg.db.DeleteModerationTerm(r.Context(), termID)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error deleting moderation term: %s", err), 400)
+			return
+		}
+		if deleted == 0 {
+			writeError(w, "moderation term not found", 404)
+			return
+		}
+		if err := cfg.reloadModerationFilter(r.Context()); err != nil {
+			writeError(w, fmt.Sprintf("error reloading moderation filter: %s", err), 500)
+			return
+		}
+		w.WriteHeader(204)
+	})
+}
+
+func (cfg *apiConfig) listFlaggedChirpsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		if !cfg.isAdmin(r) {
+			w.WriteHeader(401)
+			return
+		}
+		limit := defaultFlaggedChirpsLimit
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			parsed, err := strconv.Atoi(limitStr)
+			if err != nil || parsed < 1 {
+				writeError(w, "limit must be a positive integer", 400)
+				return
+			}
+			limit = min(parsed, maxPageLimit)
+		}
+		rows, err := cfg.db.GetFlaggedChirps(r.Context(), int32(limit))
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving flagged chirps: %s", err), 400)
+			return
+		}
+		chirps := make([]database.Chirp, 0, len(rows))
+		for _, row := range rows {
+			chirps = append(chirps, row.Chirp)
+		}
+		responses, err := cfg.chirpResponses(r.Context(), uuid.NullUUID{}, chirps)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving flagged chirps: %s", err), 400)
+			return
+		}
+		response := []flaggedChirpResponse{}
+		for i, row := range rows {
+			response = append(response, flaggedChirpResponse{
+				chirpResponse: responses[i],
+				Terms:         row.Terms,
+				FlaggedAt:     row.FlaggedAt,
+			})
+		}
+		dat, _ := json.Marshal(response)
+		w.Write(dat)
+	})
+}
+
+// dismissChirpFlagsHandler marks a flagged chirp as reviewed by removing it from the queue.
+func (cfg *apiConfig) dismissChirpFlagsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		if !cfg.isAdmin(r) {
+			w.WriteHeader(401)
+			return
+		}
+		chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+		if err != nil {
+			writeError(w, "invalid chirp id", 404)
+			return
+		}
+		cleared, err := cfg.db.ClearChirpFlags(r.Context(), chirpID)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error dismissing flags: %s", err), 400)
+			return
+		}
+		if cleared == 0 {
+			writeError(w, "chirp is not flagged", 404)
+			return
+		}
+		w.WriteHeader(204)
+	})
+}
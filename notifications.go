@@ -0,0 +1,291 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/Kurlgargyey/chirpy/internal/pubsub"
+	"github.com/google/uuid"
+)
+
+const (
+	notificationReply   = "reply"
+	notificationMention = "mention"
+	notificationLike    = "like"
+	notificationRechirp = "rechirp"
+	notificationQuote   = "quote"
+
+	topicNotifications       = "notifications"
+	eventNotificationCreated = "notification_created"
+)
+
+var notificationTypes = map[string]bool{
+	notificationReply:   true,
+	notificationMention: true,
+	notificationLike:    true,
+	notificationRechirp: true,
+	notificationQuote:   true,
+}
+
+type notificationResponse struct {
+	ID        uuid.UUID  `json:"id"`
+	Type      string     `json:"type"`
+	ActorID   uuid.UUID  `json:"actor_id"`
+	ChirpID   uuid.UUID  `json:"chirp_id"`
+	CreatedAt time.Time  `json:"created_at"`
+	ReadAt    *time.Time `json:"read_at"`
+}
+
+type notificationPageResponse struct {
+	Notifications []notificationResponse `json:"notifications"`
+	NextCursor    string                 `json:"next_cursor,omitempty"`
+}
+
+type unreadCountResponse struct {
+	Count int64 `json:"count"`
+}
+
+// notify records that actorID interacted with userID's content and returns the notification,
+// if one was created. Nobody is notified about their own actions, and the query skips actors
+// the user has blocked or muted as well as repeats of an existing notification.
+func notify(ctx context.Context, q *database.Queries, userID, actorID uuid.UUID, notificationType string, chirpID uuid.UUID) ([]database.Notification, error) {
+	if userID == actorID {
+		return nil, nil
+	}
+	notification, err := q.CreateNotification(ctx, database.CreateNotificationParams{
+		UserID:  userID,
+		ActorID: actorID,
+		Type:    notificationType,
+		ChirpID: chirpID,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []database.Notification{notification}, nil
+}
+
+// notifyChirp notifies the authors of the chirp being replied to or quoted, and the users
+// mentioned in a new chirp. Someone who is already notified about the chirp as its parent's
+// author is not notified again for being mentioned in it.
+func notifyChirp(ctx context.Context, q *database.Queries, chirp database.Chirp) ([]database.Notification, error) {
+	var created []database.Notification
+	notified := map[uuid.UUID]bool{}
+	related := []struct {
+		id               uuid.NullUUID
+		notificationType string
+	}{
+		{chirp.ReplyTo, notificationReply},
+		{chirp.QuoteOf, notificationQuote},
+	}
+	for _, rel := range related {
+		if !rel.id.Valid {
+			continue
+		}
+		parent, err := q.GetChirp(ctx, rel.id.UUID)
+		if err != nil {
+			return nil, err
+		}
+		notifications, err := notify(ctx, q, parent.UserID, chirp.UserID, rel.notificationType, chirp.ID)
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, notifications...)
+		notified[parent.UserID] = true
+	}
+	notifications, err := notifyMentions(ctx, q, chirp, notified)
+	if err != nil {
+		return nil, err
+	}
+	return append(created, notifications...), nil
+}
+
+// notifyEditedChirp notifies the users an edit newly mentions. Like notifyChirp, it leaves
+// out the authors of the chirps being replied to or quoted.
+func notifyEditedChirp(ctx context.Context, q *database.Queries, chirp database.Chirp, previous []database.ChirpMention) ([]database.Notification, error) {
+	skip := map[uuid.UUID]bool{}
+	for _, mention := range previous {
+		skip[mention.UserID] = true
+	}
+	for _, parentID := range []uuid.NullUUID{chirp.ReplyTo, chirp.QuoteOf} {
+		if !parentID.Valid {
+			continue
+		}
+		parent, err := q.GetChirp(ctx, parentID.UUID)
+		if err != nil {
+			return nil, err
+		}
+		skip[parent.UserID] = true
+	}
+	return notifyMentions(ctx, q, chirp, skip)
+}
+
+// notifyMentions notifies the users mentioned in chirp who are not in skip, and adds them to it.
+func notifyMentions(ctx context.Context, q *database.Queries, chirp database.Chirp, skip map[uuid.UUID]bool) ([]database.Notification, error) {
+	mentions, err := q.GetChirpMentions(ctx, []uuid.UUID{chirp.ID})
+	if err != nil {
+		return nil, err
+	}
+	var created []database.Notification
+	for _, mention := range mentions {
+		if skip[mention.UserID] {
+			continue
+		}
+		notifications, err := notify(ctx, q, mention.UserID, chirp.UserID, notificationMention, chirp.ID)
+		if err != nil {
+			return nil, err
+		}
+		created = append(created, notifications...)
+		skip[mention.UserID] = true
+	}
+	return created, nil
+}
+
+func newNotificationResponse(notification database.Notification) notificationResponse {
+	response := notificationResponse{
+		ID:        notification.ID,
+		Type:      notification.Type,
+		ActorID:   notification.ActorID,
+		ChirpID:   notification.ChirpID,
+		CreatedAt: notification.CreatedAt,
+	}
+	if notification.ReadAt.Valid {
+		response.ReadAt = &notification.ReadAt.Time
+	}
+	return response
+}
+
+// publishNotifications pushes notifications to their recipients' live connections.
+// Call it only once the transaction that created them has committed.
+func (cfg *apiConfig) publishNotifications(notifications []database.Notification) {
+	for _, notification := range notifications {
+		dat, _ := json.Marshal(newNotificationResponse(notification))
+		cfg.events.Publish(pubsub.Event{
+			Topic:  topicNotifications,
+			Type:   eventNotificationCreated,
+			UserID: notification.UserID,
+			Data:   dat,
+		})
+	}
+}
+
+func (cfg *apiConfig) getNotificationsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		limit, cursor, err := parsePageParams(r)
+		if err != nil {
+			writeError(w, err.Error(), 400)
+			return
+		}
+		var notificationType sql.NullString
+		if typeStr := r.URL.Query().Get("type"); typeStr != "" {
+			if !notificationTypes[typeStr] {
+				writeError(w, "type must be one of reply, mention, like, rechirp or quote", 400)
+				return
+			}
+			notificationType = sql.NullString{String: typeStr, Valid: true}
+		}
+		notifications, err := cfg.db.GetNotificationsPage(r.Context(), database.GetNotificationsPageParams{
+			UserID:          userID,
+			Type:            notificationType,
+			CursorCreatedAt: cursor.CreatedAt,
+			CursorID:        cursor.ID,
+			PageLimit:       limit + 1,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving notifications: %s", err), 400)
+			return
+		}
+		response := notificationPageResponse{Notifications: []notificationResponse{}}
+		if len(notifications) > int(limit) {
+			notifications = notifications[:limit]
+			last := notifications[len(notifications)-1]
+			response.NextCursor = encodeCursor(last.CreatedAt, last.ID)
+		}
+		for _, notification := range notifications {
+			response.Notifications = append(response.Notifications, newNotificationResponse(notification))
+		}
+		dat, _ := json.Marshal(response)
+		w.Write(dat)
+	})
+}
+
+func (cfg *apiConfig) unreadNotificationCountHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		count, err := cfg.db.CountUnreadNotifications(r.Context(), userID)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error counting notifications: %s", err), 400)
+			return
+		}
+		dat, _ := json.Marshal(unreadCountResponse{Count: count})
+		w.Write(dat)
+	})
+}
+
+func (cfg *apiConfig) markNotificationReadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		notificationID, err := uuid.Parse(r.PathValue("notificationID"))
+		if err != nil {
+			writeError(w, "invalid notification id", 404)
+			return
+		}
+		rows, err := cfg.db.MarkNotificationRead(r.Context(), database.MarkNotificationReadParams{
+			ID:     notificationID,
+			UserID: userID,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error marking notification read: %s", err), 400)
+			return
+		}
+		if rows == 0 {
+			writeError(w, "notification not found", 404)
+			return
+		}
+		w.WriteHeader(204)
+	})
+}
+
+func (cfg *apiConfig) markAllNotificationsReadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		if err := cfg.db.MarkAllNotificationsRead(r.Context(), userID); err != nil {
+			writeError(w, fmt.Sprintf("error marking notifications read: %s", err), 400)
+			return
+		}
+		w.WriteHeader(204)
+	})
+}
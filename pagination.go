@@ -0,0 +1,82 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 100
+)
+
+type pageCursor struct {
+	CreatedAt sql.NullTime
+	ID        uuid.NullUUID
+}
+
+// cursors are opaque to clients: base64url("<created_at RFC3339Nano>|<id>")
+func encodeCursor(createdAt time.Time, id uuid.UUID) string {
+	raw := fmt.Sprintf("%s|%s", createdAt.UTC().Format(time.RFC3339Nano), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(cursor string) (pageCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("malformed cursor")
+	}
+	createdAtStr, idStr, found := strings.Cut(string(raw), "|")
+	if !found {
+		return pageCursor{}, fmt.Errorf("malformed cursor")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, createdAtStr)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("malformed cursor")
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return pageCursor{}, fmt.Errorf("malformed cursor")
+	}
+	return pageCursor{
+		CreatedAt: sql.NullTime{Time: createdAt, Valid: true},
+		ID:        uuid.NullUUID{UUID: id, Valid: true},
+	}, nil
+}
+
+// parsePageParams reads the `limit` and `cursor` query parameters.
+// A missing cursor yields a zero pageCursor, which the keyset queries treat as "first page".
+func parsePageParams(r *http.Request) (int32, pageCursor, error) {
+	limit, err := parsePageLimit(r)
+	if err != nil {
+		return 0, pageCursor{}, err
+	}
+	var cursor pageCursor
+	if cursorStr := r.URL.Query().Get("cursor"); cursorStr != "" {
+		decoded, err := decodeCursor(cursorStr)
+		if err != nil {
+			return 0, pageCursor{}, err
+		}
+		cursor = decoded
+	}
+	return limit, cursor, nil
+}
+
+func parsePageLimit(r *http.Request) (int32, error) {
+	limit := defaultPageLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		parsed, err := strconv.Atoi(limitStr)
+		if err != nil || parsed < 1 {
+			return 0, fmt.Errorf("limit must be a positive integer")
+		}
+		limit = min(parsed, maxPageLimit)
+	}
+	return int32(limit), nil
+}
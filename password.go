@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Kurlgargyey/chirpy/internal/auth"
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/Kurlgargyey/chirpy/internal/mail"
+)
+
+const (
+	passwordResetLifetime = time.Hour
+	// how long sending a reset email may take once the request has been answered
+	passwordResetSendTimeout = time.Minute
+)
+
+type forgotPasswordRequestBody struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordRequestBody struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// forgotPasswordHandler always answers 202 so the endpoint can't be used to find out
+// which addresses have accounts. The lookup and the email happen after the response, so
+// neither errors nor response times differ between addresses.
+func (cfg *apiConfig) forgotPasswordHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		var requestBody forgotPasswordRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			writeError(w, fmt.Sprintf("error decoding json: %s", err), 400)
+			return
+		}
+		go func() {
+			ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), passwordResetSendTimeout)
+			defer cancel()
+			cfg.sendPasswordReset(ctx, requestBody.Email)
+		}()
+		w.WriteHeader(202)
+	})
+}
+
+// sendPasswordReset emails a reset token if email belongs to an account. Nobody is waiting
+// for the outcome, so errors are only logged.
+func (cfg *apiConfig) sendPasswordReset(ctx context.Context, email string) {
+	user, err := cfg.db.GetUser(ctx, email)
+	if errors.Is(err, sql.ErrNoRows) {
+		return
+	}
+	if err != nil {
+		fmt.Println("error looking up user for password reset:", err)
+		return
+	}
+	token, err := auth.MakeRefreshToken()
+	if err != nil {
+		fmt.Println("error generating reset token:", err)
+		return
+	}
+	err = cfg.db.CreatePasswordResetToken(ctx, database.CreatePasswordResetTokenParams{
+		TokenHash: auth.HashToken(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().UTC().Add(passwordResetLifetime),
+	})
+	if err != nil {
+		fmt.Println("error storing reset token:", err)
+		return
+	}
+	err = cfg.mailer.Send(ctx, mail.Message{
+		To:      user.Email,
+		Subject: "Reset your Chirpy password",
+		Body: fmt.Sprintf("Someone asked to reset the password for your Chirpy account.\n\n"+
+			"Your reset token is:\n\n    %s\n\n"+
+			"It can be used once within the next hour. If this wasn't you, you can ignore this email.\n", token),
+	})
+	if err != nil {
+		fmt.Println("error sending password reset email:", err)
+	}
+}
+
+// resetPasswordHandler sets a new password and logs the account out everywhere,
+// since whoever held the old password may still hold a session.
+func (cfg *apiConfig) resetPasswordHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		var requestBody resetPasswordRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			writeError(w, fmt.Sprintf("error decoding json: %s", err), 400)
+			return
+		}
+		if requestBody.Password == "" {
+			writeError(w, "password must not be empty", 400)
+			return
+		}
+		hashedPassword, err := auth.HashPassword(requestBody.Password)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error hashing password: %s", err), 400)
+			return
+		}
+		err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+			userID, err := q.ConsumePasswordResetToken(r.Context(), database.ConsumePasswordResetTokenParams{
+				TokenHash: auth.HashToken(requestBody.Token),
+				ExpiresAt: time.Now().UTC(),
+			})
+			if err != nil {
+				return err
+			}
+			err = q.SetPassword(r.Context(), database.SetPasswordParams{
+				ID:             userID,
+				HashedPassword: hashedPassword,
+			})
+			if err != nil {
+				return err
+			}
+			if err := q.InvalidatePasswordResetTokens(r.Context(), userID); err != nil {
+				return err
+			}
+			return q.RevokeAllRefreshTokens(r.Context(), userID)
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, "invalid or expired reset token", 400)
+			return
+		}
+		if err != nil {
+			writeError(w, fmt.Sprintf("error resetting password: %s", err), 400)
+			return
+		}
+		w.WriteHeader(204)
+	})
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const maxAvatarSize = 2 << 20
+
+// content types are sniffed from the upload, not taken from the request headers
+var avatarExtensions = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+type profileResponse struct {
+	DisplayName string  `json:"display_name"`
+	Bio         string  `json:"bio"`
+	Location    string  `json:"location"`
+	Website     string  `json:"website"`
+	AvatarURL   *string `json:"avatar_url"`
+}
+
+// publicUserResponse is what anyone may see about a user; it must never include the email address.
+type publicUserResponse struct {
+	ID          uuid.UUID `json:"id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Username    *string   `json:"username"`
+	IsChirpyRed bool      `json:"is_chirpy_red"`
+	profileResponse
+}
+
+func (cfg *apiConfig) profileResponse(user database.User) profileResponse {
+	response := profileResponse{
+		DisplayName: user.DisplayName,
+		Bio:         user.Bio,
+		Location:    user.Location,
+		Website:     user.Website,
+	}
+	if user.AvatarKey != "" {
+		avatarURL := cfg.avatars.URL(user.AvatarKey)
+		response.AvatarURL = &avatarURL
+	}
+	return response
+}
+
+func (cfg *apiConfig) getUserProfileHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := uuid.Parse(r.PathValue("userID"))
+		if err != nil {
+			writeError(w, "invalid user id", 400)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil || user.DeletedAt.Valid {
+			writeError(w, "user not found", 404)
+			return
+		}
+		response := publicUserResponse{
+			ID:              user.ID,
+			CreatedAt:       user.CreatedAt,
+			IsChirpyRed:     user.IsChirpyRed,
+			profileResponse: cfg.profileResponse(user),
+		}
+		if user.Username.Valid {
+			response.Username = &user.Username.String
+		}
+		dat, _ := json.Marshal(response)
+		w.WriteHeader(200)
+		w.Write(dat)
+	})
+}
+
+// uploadAvatarHandler takes the raw image as the request body.
+func (cfg *apiConfig) uploadAvatarHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		image, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxAvatarSize))
+		if err != nil {
+			writeError(w, fmt.Sprintf("avatar must be at most %d bytes", maxAvatarSize), 413)
+			return
+		}
+		extension, ok := avatarExtensions[http.DetectContentType(image)]
+		if !ok {
+			writeError(w, "avatar must be a PNG, JPEG, GIF or WebP image", 415)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		// a fresh key per upload, so caches never serve the previous avatar
+		key := uuid.New().String() + extension
+		if err := cfg.avatars.Put(r.Context(), key, bytes.NewReader(image)); err != nil {
+			writeError(w, fmt.Sprintf("error storing avatar: %s", err), 500)
+			return
+		}
+		if err := cfg.db.SetAvatarKey(r.Context(), database.SetAvatarKeyParams{ID: userID, AvatarKey: key}); err != nil {
+			cfg.avatars.Delete(r.Context(), key)
+			writeError(w, fmt.Sprintf("error saving avatar: %s", err), 400)
+			return
+		}
+		if user.AvatarKey != "" {
+			cfg.avatars.Delete(r.Context(), user.AvatarKey)
+		}
+		user.AvatarKey = key
+		dat, _ := json.Marshal(cfg.profileResponse(user))
+		w.WriteHeader(200)
+		w.Write(dat)
+	})
+}
+
+func (cfg *apiConfig) deleteAvatarHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		if user.AvatarKey == "" {
+			w.WriteHeader(204)
+			return
+		}
+		if err := cfg.db.SetAvatarKey(r.Context(), database.SetAvatarKeyParams{ID: userID}); err != nil {
+			writeError(w, fmt.Sprintf("error removing avatar: %s", err), 400)
+			return
+		}
+		cfg.avatars.Delete(r.Context(), user.AvatarKey)
+		w.WriteHeader(204)
+	})
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type quoteRequestBody struct {
+	Body *string `json:"body"`
+}
+
+// originalChirpID resolves the chirp named in the path, following a rechirp back to the chirp it amplifies.
+func (cfg *apiConfig) originalChirpID(r *http.Request) (uuid.UUID, error) {
+	chirpID, err := uuid.Parse(r.PathValue("chirpID"))
+	if err != nil {
+		return uuid.Nil, err
+	}
+	chirp, err := cfg.db.GetChirp(r.Context(), chirpID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if chirp.RechirpOf.Valid {
+		return chirp.RechirpOf.UUID, nil
+	}
+	return chirp.ID, nil
+}
+
+// originalChirp is originalChirpID for chirps that can still be rechirped or quoted, which
+// excludes those of accounts awaiting deletion.
+func (cfg *apiConfig) originalChirp(r *http.Request) (database.Chirp, error) {
+	originalID, err := cfg.originalChirpID(r)
+	if err != nil {
+		return database.Chirp{}, err
+	}
+	return cfg.db.GetVisibleChirp(r.Context(), database.GetVisibleChirpParams{ID: originalID})
+}
+
+func (cfg *apiConfig) rechirpHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		if !cfg.canPost(w, r, userID) {
+			return
+		}
+		original, err := cfg.originalChirp(r)
+		if err != nil {
+			writeError(w, "chirp not found", 404)
+			return
+		}
+		blocked, err := cfg.isBlockedBy(r.Context(), original.UserID, userID)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error checking blocks: %s", err), 500)
+			return
+		}
+		if blocked {
+			writeError(w, "you cannot rechirp this user's chirps", 403)
+			return
+		}
+		var rechirp database.Chirp
+		var notifications []database.Notification
+		err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+			var err error
+			rechirp, err = q.CreateRechirp(r.Context(), database.CreateRechirpParams{
+				UserID:    userID,
+				RechirpOf: uuid.NullUUID{UUID: original.ID, Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+			notifications, err = notify(r.Context(), q, original.UserID, userID, notificationRechirp, original.ID)
+			return err
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, "chirp has already been rechirped", 409)
+			return
+		}
+		if err != nil {
+			writeError(w, fmt.Sprintf("error rechirping chirp: %s", err), 400)
+			return
+		}
+		cfg.publishNotifications(notifications)
+		cfg.publishChirpCreated(r.Context(), rechirp)
+		response, err := cfg.chirpResponse(r.Context(), cfg.viewerID(r), rechirp)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving chirp: %s", err), 400)
+			return
+		}
+		dat, _ := json.Marshal(response)
+		w.WriteHeader(201)
+		w.Write(dat)
+	})
+}
+
+func (cfg *apiConfig) undoRechirpHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		originalID, err := cfg.originalChirpID(r)
+		if err != nil {
+			writeError(w, "chirp not found", 404)
+			return
+		}
+		rechirp, err := cfg.db.DeleteRechirp(r.Context(), database.DeleteRechirpParams{
+			UserID:    userID,
+			RechirpOf: uuid.NullUUID{UUID: originalID, Valid: true},
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, "chirp has not been rechirped", 404)
+			return
+		}
+		if err != nil {
+			writeError(w, fmt.Sprintf("error undoing rechirp: %s", err), 400)
+			return
+		}
+		cfg.publishChirpDeleted(rechirp)
+		w.WriteHeader(204)
+	})
+}
+
+func (cfg *apiConfig) quoteChirpHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		if !cfg.canPost(w, r, userID) {
+			return
+		}
+		original, err := cfg.originalChirp(r)
+		if err != nil {
+			writeError(w, "chirp not found", 404)
+			return
+		}
+		blocked, err := cfg.isBlockedBy(r.Context(), original.UserID, userID)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error checking blocks: %s", err), 500)
+			return
+		}
+		if blocked {
+			writeError(w, "you cannot quote this user's chirps", 403)
+			return
+		}
+		var requestBody quoteRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			writeError(w, fmt.Sprintf("error decoding json: %s", err), 400)
+			return
+		}
+		cleanedBody, errs := cfg.validateChirpBody(requestBody.Body)
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+		var quote database.Chirp
+		var notifications []database.Notification
+		err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+			var err error
+			quote, err = q.CreateQuoteChirp(r.Context(), database.CreateQuoteChirpParams{
+				Body:    cleanedBody,
+				UserID:  userID,
+				QuoteOf: uuid.NullUUID{UUID: original.ID, Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+			if err := cfg.indexChirp(r.Context(), q, quote); err != nil {
+				return err
+			}
+			notifications, err = notifyChirp(r.Context(), q, quote)
+			return err
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error quoting chirp: %s", err), 400)
+			return
+		}
+		cfg.publishNotifications(notifications)
+		cfg.publishChirpCreated(r.Context(), quote)
+		response, err := cfg.chirpResponse(r.Context(), cfg.viewerID(r), quote)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving chirp: %s", err), 400)
+			return
+		}
+		dat, _ := json.Marshal(response)
+		w.WriteHeader(201)
+		w.Write(dat)
+	})
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Kurlgargyey/chirpy/internal/chirptext"
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+type searchResultResponse struct {
+	chirpResponse
+	// Snippet is an HTML-escaped excerpt of the body with matches wrapped in <mark></mark>.
+	Snippet string   `json:"snippet"`
+	Rank    *float32 `json:"rank,omitempty"`
+}
+
+type searchPageResponse struct {
+	Chirps     []searchResultResponse `json:"chirps"`
+	NextCursor string                 `json:"next_cursor,omitempty"`
+}
+
+type searchFilters struct {
+	query    string
+	authorID uuid.NullUUID
+	since    sql.NullTime
+	until    sql.NullTime
+}
+
+// relevance-ordered results page on (rank, id) instead of (created_at, id)
+func encodeRankCursor(rank float32, id uuid.UUID) string {
+	raw := fmt.Sprintf("%s|%s", strconv.FormatFloat(float64(rank), 'g', -1, 32), id)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeRankCursor(cursor string) (sql.NullFloat64, uuid.NullUUID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return sql.NullFloat64{}, uuid.NullUUID{}, fmt.Errorf("malformed cursor")
+	}
+	rankStr, idStr, found := strings.Cut(string(raw), "|")
+	if !found {
+		return sql.NullFloat64{}, uuid.NullUUID{}, fmt.Errorf("malformed cursor")
+	}
+	rank, err := strconv.ParseFloat(rankStr, 32)
+	if err != nil {
+		return sql.NullFloat64{}, uuid.NullUUID{}, fmt.Errorf("malformed cursor")
+	}
+	id, err := uuid.Parse(idStr)
+	if err != nil {
+		return sql.NullFloat64{}, uuid.NullUUID{}, fmt.Errorf("malformed cursor")
+	}
+	return sql.NullFloat64{Float64: rank, Valid: true}, uuid.NullUUID{UUID: id, Valid: true}, nil
+}
+
+func parseSearchFilters(r *http.Request) (searchFilters, error) {
+	query := r.URL.Query()
+	filters := searchFilters{query: strings.TrimSpace(query.Get("q"))}
+	if filters.query == "" {
+		return searchFilters{}, fmt.Errorf("missing required query parameter: q")
+	}
+	if authorIDStr := query.Get("author_id"); authorIDStr != "" {
+		authorID, err := uuid.Parse(authorIDStr)
+		if err != nil {
+			return searchFilters{}, fmt.Errorf("invalid author_id")
+		}
+		filters.authorID = uuid.NullUUID{UUID: authorID, Valid: true}
+	}
+	for name, target := range map[string]*sql.NullTime{"since": &filters.since, "until": &filters.until} {
+		if value := query.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return searchFilters{}, fmt.Errorf("%s must be an RFC 3339 timestamp", name)
+			}
+			*target = sql.NullTime{Time: parsed.UTC(), Valid: true}
+		}
+	}
+	return filters, nil
+}
+
+func (cfg *apiConfig) searchChirpsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		filters, err := parseSearchFilters(r)
+		if err != nil {
+			writeError(w, err.Error(), 400)
+			return
+		}
+		limit, err := parsePageLimit(r)
+		if err != nil {
+			writeError(w, err.Error(), 400)
+			return
+		}
+		viewerID := cfg.viewerID(r)
+		var chirps []database.Chirp
+		var snippets []string
+		var ranks []float32
+		var nextCursor string
+		switch r.URL.Query().Get("order") {
+		case "", "relevance":
+			var cursorRank sql.NullFloat64
+			var cursorID uuid.NullUUID
+			if cursorStr := r.URL.Query().Get("cursor"); cursorStr != "" {
+				cursorRank, cursorID, err = decodeRankCursor(cursorStr)
+				if err != nil {
+					writeError(w, err.Error(), 400)
+					return
+				}
+			}
+			rows, err := cfg.db.SearchChirpsByRelevance(r.Context(), database.SearchChirpsByRelevanceParams{
+				Query:      filters.query,
+				AuthorID:   filters.authorID,
+				Since:      filters.since,
+				Until:      filters.until,
+				CursorRank: cursorRank,
+				CursorID:   cursorID,
+				ViewerID:   viewerID,
+				PageLimit:  limit + 1,
+			})
+			if err != nil {
+				writeError(w, fmt.Sprintf("error searching chirps: %s", err), 400)
+				return
+			}
+			if len(rows) > int(limit) {
+				rows = rows[:limit]
+				last := rows[len(rows)-1]
+				nextCursor = encodeRankCursor(last.Rank, last.Chirp.ID)
+			}
+			for _, row := range rows {
+				chirps = append(chirps, row.Chirp)
+				snippets = append(snippets, chirptext.Highlight(row.Snippet))
+				ranks = append(ranks, row.Rank)
+			}
+		case "recency":
+			cursor := pageCursor{}
+			if cursorStr := r.URL.Query().Get("cursor"); cursorStr != "" {
+				cursor, err = decodeCursor(cursorStr)
+				if err != nil {
+					writeError(w, err.Error(), 400)
+					return
+				}
+			}
+			rows, err := cfg.db.SearchChirpsByRecency(r.Context(), database.SearchChirpsByRecencyParams{
+				Query:           filters.query,
+				AuthorID:        filters.authorID,
+				Since:           filters.since,
+				Until:           filters.until,
+				CursorCreatedAt: cursor.CreatedAt,
+				CursorID:        cursor.ID,
+				ViewerID:        viewerID,
+				PageLimit:       limit + 1,
+			})
+			if err != nil {
+				writeError(w, fmt.Sprintf("error searching chirps: %s", err), 400)
+				return
+			}
+			if len(rows) > int(limit) {
+				rows = rows[:limit]
+				last := rows[len(rows)-1]
+				nextCursor = encodeCursor(last.Chirp.CreatedAt, last.Chirp.ID)
+			}
+			for _, row := range rows {
+				chirps = append(chirps, row.Chirp)
+				snippets = append(snippets, chirptext.Highlight(row.Snippet))
+			}
+		default:
+			writeError(w, "order must be relevance or recency", 400)
+			return
+		}
+		responses, err := cfg.chirpResponses(r.Context(), viewerID, chirps)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error searching chirps: %s", err), 400)
+			return
+		}
+		response := searchPageResponse{
+			Chirps:     []searchResultResponse{},
+			NextCursor: nextCursor,
+		}
+		for i := range responses {
+			result := searchResultResponse{
+				chirpResponse: responses[i],
+				Snippet:       snippets[i],
+			}
+			if ranks != nil {
+				result.Rank = &ranks[i]
+			}
+			response.Chirps = append(response.Chirps, result)
+		}
+		dat, _ := json.Marshal(response)
+		w.Write(dat)
+	})
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+// A session is one login: the family of refresh tokens descending from it.
+type sessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+}
+
+// startSession records a new login and returns the first refresh token of its family.
+func startSession(r *http.Request, q *database.Queries, userID uuid.UUID) (string, error) {
+	session, err := q.CreateSession(r.Context(), database.CreateSessionParams{
+		ID:        uuid.New(),
+		UserID:    userID,
+		UserAgent: r.UserAgent(),
+		IpAddress: clientIP(r),
+	})
+	if err != nil {
+		return "", err
+	}
+	return issueRefreshToken(r.Context(), q, userID, session.ID)
+}
+
+// clientIP is the address of the direct peer; proxy headers are not trusted.
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func (cfg *apiConfig) getSessionsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		sessions, err := cfg.db.GetActiveSessions(r.Context(), database.GetActiveSessionsParams{
+			UserID:    userID,
+			ExpiresAt: time.Now().UTC(),
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving sessions: %s", err), 400)
+			return
+		}
+		response := make([]sessionResponse, 0, len(sessions))
+		for _, session := range sessions {
+			response = append(response, sessionResponse{
+				ID:         session.ID,
+				CreatedAt:  session.CreatedAt,
+				LastUsedAt: session.LastUsedAt,
+				UserAgent:  session.UserAgent,
+				IPAddress:  session.IpAddress,
+			})
+		}
+		dat, _ := json.Marshal(response)
+		w.WriteHeader(200)
+		w.Write(dat)
+	})
+}
+
+func (cfg *apiConfig) revokeSessionHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		sessionID, err := uuid.Parse(r.PathValue("sessionID"))
+		if err != nil {
+			writeError(w, "invalid session id", 400)
+			return
+		}
+		revoked, err := cfg.db.RevokeSession(r.Context(), database.RevokeSessionParams{
+			FamilyID: sessionID,
+			UserID:   userID,
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error revoking session: %s", err), 400)
+			return
+		}
+		if revoked == 0 {
+			writeError(w, "no active session with that id", 404)
+			return
+		}
+		w.WriteHeader(204)
+	})
+}
+
+// revokeAllSessionsHandler logs the user out everywhere, including the calling device.
+// Access tokens already issued stay valid until they expire.
+func (cfg *apiConfig) revokeAllSessionsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		if err := cfg.db.RevokeAllRefreshTokens(r.Context(), userID); err != nil {
+			writeError(w, fmt.Sprintf("error revoking sessions: %s", err), 400)
+			return
+		}
+		w.WriteHeader(204)
+	})
+}
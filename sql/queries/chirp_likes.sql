@@ -0,0 +1,44 @@
+-- name: LikeChirp :exec
+INSERT INTO chirp_likes (user_id, chirp_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnlikeChirp :exec
+DELETE FROM chirp_likes
+WHERE user_id = $1 AND chirp_id = $2;
+
+-- name: GetLikeCounts :many
+SELECT chirp_id, COUNT(*) AS like_count FROM chirp_likes
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirp_likes.user_id AND users.deleted_at IS NOT NULL
+)
+GROUP BY chirp_id;
+
+-- name: GetLikedChirpIDs :many
+SELECT chirp_id FROM chirp_likes
+WHERE user_id = sqlc.arg('user_id')
+AND chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[]);
+
+-- name: GetUserLikesPage :many
+SELECT sqlc.embed(chirps), chirp_likes.created_at AS liked_at FROM chirp_likes
+JOIN chirps ON chirps.id = chirp_likes.chirp_id
+WHERE chirp_likes.user_id = sqlc.arg('user_id')
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (chirp_likes.created_at, chirp_likes.chirp_id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid))
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+ORDER BY chirp_likes.created_at DESC, chirp_likes.chirp_id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetAllUserLikes :many
+SELECT * FROM chirp_likes
+WHERE user_id = $1
+ORDER BY created_at ASC;
@@ -0,0 +1,39 @@
+-- name: CreateChirpMention :exec
+INSERT INTO chirp_mentions (chirp_id, user_id, start_offset, end_offset, created_at)
+VALUES (
+	$1,
+	$2,
+	$3,
+	$4,
+	$5
+)
+ON CONFLICT DO NOTHING;
+
+-- name: ClearChirpMentions :exec
+DELETE FROM chirp_mentions
+WHERE chirp_id = $1;
+
+-- name: GetChirpMentions :many
+SELECT * FROM chirp_mentions
+WHERE chirp_id = ANY(sqlc.arg('chirp_ids')::uuid[])
+ORDER BY chirp_id, start_offset;
+
+-- name: GetMentionsPage :many
+SELECT * FROM chirps
+WHERE id IN (SELECT chirp_id FROM chirp_mentions WHERE chirp_mentions.user_id = sqlc.arg('user_id'))
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid))
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = sqlc.arg('user_id') AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = sqlc.arg('user_id') AND muted_id = chirps.user_id
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit');
@@ -1,36 +1,243 @@
 -- name: CreateChirp :one
-INSERT INTO chirps (id, created_at, updated_at, body, user_id)
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, reply_to)
 VALUES (
     gen_random_uuid(),
 	NOW(),
 	NOW(),
 	$1,
-	$2
+	$2,
+	$3
 )
 RETURNING *;
 
--- name: GetAllChirpsAsc :many
-SELECT * FROM chirps
-ORDER BY created_at ASC;
-
 -- name: GetUserChirpsAsc :many
 SELECT * FROM chirps
 WHERE user_id = $1
 ORDER BY created_at ASC;
 
--- name: GetAllChirpsDesc :many
+-- name: GetChirp :one
 SELECT * FROM chirps
-ORDER BY created_at DESC;
+WHERE id = $1;
 
--- name: GetUserChirpsDesc :many
+-- name: GetChirpForUpdate :one
 SELECT * FROM chirps
-WHERE user_id = $1
-ORDER BY created_at DESC;
+WHERE id = $1
+FOR UPDATE;
 
--- name: GetChirp :one
+-- name: GetVisibleChirp :one
 SELECT * FROM chirps
-WHERE id = $1;
+WHERE id = sqlc.arg('id')
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = sqlc.narg('viewer_id')::uuid AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = sqlc.narg('viewer_id')::uuid AND muted_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+);
 
 -- name: DeleteChirp :exec
 DELETE FROM chirps
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: DeleteChirpsOfPurgedUsers :many
+DELETE FROM chirps
+WHERE user_id IN (SELECT id FROM users WHERE deleted_at < $1)
+OR rechirp_of IN (
+	SELECT chirps.id FROM chirps
+	JOIN users ON users.id = chirps.user_id
+	WHERE users.deleted_at < $1
+)
+RETURNING *;
+
+-- name: GetChirpsPageAsc :many
+SELECT * FROM chirps
+WHERE (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, id) > (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid))
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = sqlc.narg('viewer_id')::uuid AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = sqlc.narg('viewer_id')::uuid AND muted_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetChirpsPageDesc :many
+SELECT * FROM chirps
+WHERE (sqlc.narg('author_id')::uuid IS NULL OR user_id = sqlc.narg('author_id'))
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid))
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = sqlc.narg('viewer_id')::uuid AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = sqlc.narg('viewer_id')::uuid AND muted_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: UpdateChirp :one
+UPDATE chirps
+SET body = $2,
+	updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+
+-- name: GetRepliesPage :many
+SELECT * FROM chirps
+WHERE reply_to = sqlc.arg('reply_to')
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, id) > (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid))
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = sqlc.narg('viewer_id')::uuid AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = sqlc.narg('viewer_id')::uuid AND muted_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+ORDER BY created_at ASC, id ASC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetReplyCounts :many
+SELECT reply_to, COUNT(*) AS reply_count FROM chirps
+WHERE reply_to = ANY(sqlc.arg('chirp_ids')::uuid[])
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+GROUP BY reply_to;
+
+-- name: GetChirpAncestors :many
+WITH RECURSIVE ancestors(id, reply_to, depth) AS (
+	SELECT chirps.id, chirps.reply_to, 0 FROM chirps
+	WHERE chirps.id = sqlc.arg('chirp_id')::uuid
+	UNION ALL
+	SELECT parent.id, parent.reply_to, ancestors.depth + 1 FROM chirps parent
+	JOIN ancestors ON parent.id = ancestors.reply_to
+)
+SELECT chirps.* FROM chirps
+JOIN ancestors ON chirps.id = ancestors.id
+WHERE ancestors.depth > 0
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = sqlc.narg('viewer_id')::uuid AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = sqlc.narg('viewer_id')::uuid AND muted_id = chirps.user_id
+)
+ORDER BY ancestors.depth DESC;
+
+-- name: GetChirpDescendants :many
+WITH RECURSIVE descendants(id) AS (
+	SELECT chirps.id FROM chirps
+	WHERE chirps.reply_to = sqlc.arg('chirp_id')::uuid
+	UNION ALL
+	SELECT child.id FROM chirps child
+	JOIN descendants ON child.reply_to = descendants.id
+)
+SELECT chirps.* FROM chirps
+JOIN descendants ON chirps.id = descendants.id
+WHERE NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = sqlc.narg('viewer_id')::uuid AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = sqlc.narg('viewer_id')::uuid AND muted_id = chirps.user_id
+)
+ORDER BY chirps.created_at ASC, chirps.id ASC
+LIMIT sqlc.arg('max_results');
+
+-- name: CreateRechirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, rechirp_of)
+VALUES (
+    gen_random_uuid(),
+	NOW(),
+	NOW(),
+	'',
+	$1,
+	$2
+)
+ON CONFLICT (user_id, rechirp_of) WHERE rechirp_of IS NOT NULL DO NOTHING
+RETURNING *;
+
+-- name: DeleteRechirp :one
+DELETE FROM chirps
+WHERE user_id = $1 AND rechirp_of = $2
+RETURNING *;
+
+-- name: DeleteRechirpsOf :many
+DELETE FROM chirps
+WHERE rechirp_of = $1
+RETURNING *;
+
+-- name: CreateQuoteChirp :one
+INSERT INTO chirps (id, created_at, updated_at, body, user_id, quote_of, is_quote)
+VALUES (
+    gen_random_uuid(),
+	NOW(),
+	NOW(),
+	$1,
+	$2,
+	$3,
+	true
+)
+RETURNING *;
+
+-- name: GetChirpsByIDs :many
+SELECT * FROM chirps
+WHERE id = ANY(sqlc.arg('chirp_ids')::uuid[])
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = sqlc.narg('viewer_id')::uuid AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = sqlc.narg('viewer_id')::uuid AND muted_id = chirps.user_id
+);
+
+-- name: GetRechirpCounts :many
+SELECT rechirp_of, COUNT(*) AS rechirp_count FROM chirps
+WHERE rechirp_of = ANY(sqlc.arg('chirp_ids')::uuid[])
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+GROUP BY rechirp_of;
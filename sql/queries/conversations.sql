@@ -0,0 +1,69 @@
+-- name: CreateConversation :one
+INSERT INTO conversations (id, created_at, updated_at, is_group)
+VALUES (
+	gen_random_uuid(),
+	NOW(),
+	NOW(),
+	$1
+)
+RETURNING *;
+
+-- name: AddConversationParticipant :exec
+INSERT INTO conversation_participants (conversation_id, user_id, joined_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+);
+
+-- name: GetConversation :one
+SELECT * FROM conversations
+WHERE id = $1;
+
+-- name: GetDirectConversation :one
+SELECT * FROM conversations
+WHERE is_group = FALSE
+AND EXISTS (
+	SELECT 1 FROM conversation_participants
+	WHERE conversation_id = conversations.id AND user_id = sqlc.arg('user_id')
+)
+AND EXISTS (
+	SELECT 1 FROM conversation_participants
+	WHERE conversation_id = conversations.id AND user_id = sqlc.arg('other_user_id')
+);
+
+-- name: GetConversationParticipants :many
+SELECT * FROM conversation_participants
+WHERE conversation_id = ANY(sqlc.arg('conversation_ids')::uuid[])
+ORDER BY joined_at ASC, user_id ASC;
+
+-- name: GetUserConversationsPage :many
+SELECT * FROM (
+	SELECT conversations.*, (
+		SELECT COUNT(*) FROM messages
+		WHERE messages.conversation_id = conversations.id
+		AND messages.sender_id <> sqlc.arg('user_id')
+		AND (conversation_participants.last_read_at IS NULL
+			OR messages.created_at > conversation_participants.last_read_at)
+	) AS unread_count, COALESCE((
+		SELECT MAX(messages.created_at) FROM messages
+		WHERE messages.conversation_id = conversations.id
+		AND messages.created_at <= COALESCE(sqlc.narg('snapshot')::timestamp, NOW()::timestamp)
+	), conversations.created_at)::timestamp AS active_at
+	FROM conversations
+	JOIN conversation_participants ON conversation_participants.conversation_id = conversations.id
+	WHERE conversation_participants.user_id = sqlc.arg('user_id')
+	AND conversations.created_at <= COALESCE(sqlc.narg('snapshot')::timestamp, NOW()::timestamp)
+) AS page
+WHERE sqlc.narg('cursor_active_at')::timestamp IS NULL
+	OR (active_at, id) < (sqlc.narg('cursor_active_at'), sqlc.narg('cursor_id')::uuid)
+ORDER BY active_at DESC, id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: TouchConversation :exec
+UPDATE conversations SET updated_at = NOW()
+WHERE id = $1;
+
+-- name: MarkConversationRead :exec
+UPDATE conversation_participants SET last_read_at = NOW()
+WHERE conversation_id = $1 AND user_id = $2;
@@ -0,0 +1,57 @@
+-- name: FollowUser :exec
+INSERT INTO follows (follower_id, followee_id, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UnfollowUser :exec
+DELETE FROM follows
+WHERE follower_id = $1 AND followee_id = $2;
+
+-- name: GetFollowersPage :many
+SELECT * FROM follows
+WHERE followee_id = sqlc.arg('user_id')
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, follower_id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid))
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = follows.follower_id AND users.deleted_at IS NOT NULL
+)
+ORDER BY created_at DESC, follower_id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetFollowingPage :many
+SELECT * FROM follows
+WHERE follower_id = sqlc.arg('user_id')
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, followee_id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid))
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = follows.followee_id AND users.deleted_at IS NOT NULL
+)
+ORDER BY created_at DESC, followee_id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetTimelinePage :many
+SELECT * FROM chirps
+WHERE (user_id = sqlc.arg('user_id')
+	OR user_id IN (SELECT followee_id FROM follows WHERE follower_id = sqlc.arg('user_id')))
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = sqlc.arg('user_id') AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = sqlc.arg('user_id') AND muted_id = chirps.user_id
+)
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid))
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit');
@@ -0,0 +1,57 @@
+-- name: UpsertHashtag :one
+INSERT INTO hashtags (id, tag, created_at)
+VALUES (
+	gen_random_uuid(),
+	$1,
+	NOW()
+)
+ON CONFLICT (tag) DO UPDATE SET tag = EXCLUDED.tag
+RETURNING *;
+
+-- name: TagChirp :exec
+INSERT INTO chirp_hashtags (chirp_id, hashtag_id, created_at)
+VALUES (
+	$1,
+	$2,
+	$3
+)
+ON CONFLICT DO NOTHING;
+
+-- name: UntagChirp :exec
+DELETE FROM chirp_hashtags
+WHERE chirp_id = $1;
+
+-- name: GetHashtagChirpsPage :many
+SELECT chirps.* FROM chirps
+JOIN chirp_hashtags ON chirp_hashtags.chirp_id = chirps.id
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+WHERE hashtags.tag = sqlc.arg('tag')
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid))
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = sqlc.narg('viewer_id')::uuid AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = sqlc.narg('viewer_id')::uuid AND muted_id = chirps.user_id
+)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: GetTrendingHashtags :many
+SELECT hashtags.tag, COUNT(*) AS chirp_count FROM chirp_hashtags
+JOIN hashtags ON hashtags.id = chirp_hashtags.hashtag_id
+WHERE chirp_hashtags.created_at > sqlc.arg('since')
+AND NOT EXISTS (
+	SELECT 1 FROM chirps
+	JOIN users ON users.id = chirps.user_id
+	WHERE chirps.id = chirp_hashtags.chirp_id AND users.deleted_at IS NOT NULL
+)
+GROUP BY hashtags.tag
+ORDER BY chirp_count DESC, hashtags.tag ASC
+LIMIT sqlc.arg('max_results');
@@ -0,0 +1,42 @@
+-- name: GetModerationTerms :many
+SELECT * FROM moderation_terms
+ORDER BY term ASC;
+
+-- name: UpsertModerationTerm :one
+INSERT INTO moderation_terms (id, term, action, created_at, updated_at)
+VALUES (
+	gen_random_uuid(),
+	$1,
+	$2,
+	NOW(),
+	NOW()
+)
+ON CONFLICT (term) DO UPDATE SET action = EXCLUDED.action, updated_at = NOW()
+RETURNING *;
+
+-- name: DeleteModerationTerm :execrows
+DELETE FROM moderation_terms
+WHERE id = $1;
+
+-- name: FlagChirp :exec
+INSERT INTO moderation_flags (chirp_id, term, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+)
+ON CONFLICT DO NOTHING;
+
+-- name: ClearChirpFlags :execrows
+DELETE FROM moderation_flags
+WHERE chirp_id = $1;
+
+-- name: GetFlaggedChirps :many
+SELECT sqlc.embed(chirps),
+	array_agg(moderation_flags.term ORDER BY moderation_flags.term)::text[] AS terms,
+	MAX(moderation_flags.created_at)::timestamp AS flagged_at
+FROM moderation_flags
+JOIN chirps ON chirps.id = moderation_flags.chirp_id
+GROUP BY chirps.id
+ORDER BY flagged_at ASC, chirps.id ASC
+LIMIT $1;
@@ -0,0 +1,34 @@
+-- name: CreateNotification :one
+INSERT INTO notifications (id, user_id, actor_id, type, chirp_id, created_at)
+SELECT gen_random_uuid(), sqlc.arg('user_id')::uuid, sqlc.arg('actor_id')::uuid, sqlc.arg('type')::text, sqlc.arg('chirp_id')::uuid, NOW()
+WHERE NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = sqlc.arg('user_id') AND blocked_id = sqlc.arg('actor_id')
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = sqlc.arg('user_id') AND muted_id = sqlc.arg('actor_id')
+)
+ON CONFLICT DO NOTHING
+RETURNING *;
+
+-- name: GetNotificationsPage :many
+SELECT * FROM notifications
+WHERE user_id = sqlc.arg('user_id')
+AND (sqlc.narg('type')::text IS NULL OR type = sqlc.narg('type'))
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (created_at, id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid))
+ORDER BY created_at DESC, id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: CountUnreadNotifications :one
+SELECT COUNT(*) FROM notifications
+WHERE user_id = $1 AND read_at IS NULL;
+
+-- name: MarkNotificationRead :execrows
+UPDATE notifications SET read_at = COALESCE(read_at, NOW())
+WHERE id = $1 AND user_id = $2;
+
+-- name: MarkAllNotificationsRead :exec
+UPDATE notifications SET read_at = NOW()
+WHERE user_id = $1 AND read_at IS NULL;
@@ -0,0 +1,53 @@
+-- name: SearchChirpsByRecency :many
+SELECT sqlc.embed(chirps),
+	ts_headline('english', translate(chirps.body, chr(2) || chr(3), ''), websearch_to_tsquery('english', sqlc.arg('query')),
+		'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxFragments=2') AS snippet
+FROM chirps
+WHERE to_tsvector('english', chirps.body) @@ websearch_to_tsquery('english', sqlc.arg('query'))
+AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id'))
+AND (sqlc.narg('since')::timestamp IS NULL OR chirps.created_at >= sqlc.narg('since'))
+AND (sqlc.narg('until')::timestamp IS NULL OR chirps.created_at < sqlc.narg('until'))
+AND (sqlc.narg('cursor_created_at')::timestamp IS NULL
+	OR (chirps.created_at, chirps.id) < (sqlc.narg('cursor_created_at'), sqlc.narg('cursor_id')::uuid))
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = sqlc.narg('viewer_id')::uuid AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = sqlc.narg('viewer_id')::uuid AND muted_id = chirps.user_id
+)
+ORDER BY chirps.created_at DESC, chirps.id DESC
+LIMIT sqlc.arg('page_limit');
+
+-- name: SearchChirpsByRelevance :many
+SELECT sqlc.embed(chirps),
+	ts_headline('english', translate(chirps.body, chr(2) || chr(3), ''), websearch_to_tsquery('english', sqlc.arg('query')),
+		'StartSel=' || chr(2) || ', StopSel=' || chr(3) || ', MaxFragments=2') AS snippet,
+	ts_rank(to_tsvector('english', chirps.body), websearch_to_tsquery('english', sqlc.arg('query')))::real AS rank
+FROM chirps
+WHERE to_tsvector('english', chirps.body) @@ websearch_to_tsquery('english', sqlc.arg('query'))
+AND (sqlc.narg('author_id')::uuid IS NULL OR chirps.user_id = sqlc.narg('author_id'))
+AND (sqlc.narg('since')::timestamp IS NULL OR chirps.created_at >= sqlc.narg('since'))
+AND (sqlc.narg('until')::timestamp IS NULL OR chirps.created_at < sqlc.narg('until'))
+AND (sqlc.narg('cursor_rank')::real IS NULL
+	OR (ts_rank(to_tsvector('english', chirps.body), websearch_to_tsquery('english', sqlc.arg('query')))::real, chirps.id)
+		< (sqlc.narg('cursor_rank'), sqlc.narg('cursor_id')::uuid))
+AND NOT EXISTS (
+	SELECT 1 FROM users
+	WHERE users.id = chirps.user_id AND users.deleted_at IS NOT NULL
+)
+AND NOT EXISTS (
+	SELECT 1 FROM blocks
+	WHERE blocker_id = sqlc.narg('viewer_id')::uuid AND blocked_id = chirps.user_id
+)
+AND NOT EXISTS (
+	SELECT 1 FROM mutes
+	WHERE muter_id = sqlc.narg('viewer_id')::uuid AND muted_id = chirps.user_id
+)
+ORDER BY rank DESC, chirps.id DESC
+LIMIT sqlc.arg('page_limit');
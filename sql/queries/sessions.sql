@@ -0,0 +1,32 @@
+-- name: CreateSession :one
+INSERT INTO sessions (id, user_id, created_at, last_used_at, user_agent, ip_address)
+VALUES (
+	$1,
+	$2,
+	NOW(),
+	NOW(),
+	$3,
+	$4
+)
+RETURNING *;
+
+-- name: TouchSession :exec
+UPDATE sessions
+SET last_used_at = NOW()
+WHERE id = $1;
+
+-- name: GetActiveSessions :many
+SELECT * FROM sessions
+WHERE user_id = $1
+AND EXISTS (
+	SELECT 1 FROM refresh_tokens
+	WHERE refresh_tokens.family_id = sessions.id
+	AND refresh_tokens.revoked_at IS NULL
+	AND refresh_tokens.expires_at > $2
+)
+ORDER BY last_used_at DESC, id DESC;
+
+-- name: GetUserSessions :many
+SELECT * FROM sessions
+WHERE user_id = $1
+ORDER BY created_at ASC;
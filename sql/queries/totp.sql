@@ -0,0 +1,55 @@
+-- name: StartTOTPEnrollment :one
+INSERT INTO user_totp (user_id, secret, created_at, updated_at)
+VALUES (
+	$1,
+	$2,
+	NOW(),
+	NOW()
+)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret,
+	updated_at = NOW(),
+	last_used_counter = NULL
+WHERE user_totp.enabled_at IS NULL
+RETURNING *;
+
+-- name: GetTOTP :one
+SELECT * FROM user_totp
+WHERE user_id = $1;
+
+-- name: EnableTOTP :exec
+UPDATE user_totp
+SET enabled_at = NOW(),
+	updated_at = NOW(),
+	last_used_counter = $2
+WHERE user_id = $1;
+
+-- name: UseTOTPCounter :execrows
+UPDATE user_totp
+SET last_used_counter = $2,
+	updated_at = NOW()
+WHERE user_id = $1
+AND (last_used_counter IS NULL OR last_used_counter < $2);
+
+-- name: DeleteTOTP :exec
+DELETE FROM user_totp
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO totp_recovery_codes (user_id, code_hash, created_at)
+VALUES (
+	$1,
+	$2,
+	NOW()
+);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM totp_recovery_codes
+WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE totp_recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1
+AND code_hash = $2
+AND used_at IS NULL;
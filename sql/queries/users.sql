@@ -20,6 +20,7 @@ WHERE email = $1;
 UPDATE users
 SET email = $2,
 	hashed_password = $3,
+	email_verified_at = CASE WHEN email = $2 THEN email_verified_at ELSE NULL END,
 	updated_at = NOW()
 WHERE id = $1
 RETURNING *;
@@ -27,4 +28,64 @@ RETURNING *;
 -- name: UnlockRed :exec
 UPDATE users
 SET is_chirpy_red = true
-WHERE id = $1;
\ No newline at end of file
+WHERE id = $1;
+
+-- name: GetUserByID :one
+SELECT * FROM users
+WHERE id = $1;
+
+-- name: SetUsername :one
+UPDATE users
+SET username = $2,
+	updated_at = NOW()
+WHERE id = $1
+RETURNING *;
+
+-- name: GetUsersByUsernames :many
+SELECT * FROM users
+WHERE LOWER(username) = ANY(sqlc.arg('usernames')::text[]);
+
+-- name: SetPassword :exec
+UPDATE users
+SET hashed_password = $2,
+	updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetEmail :exec
+UPDATE users
+SET email = $2,
+	email_verified_at = CASE WHEN email = $2 THEN email_verified_at ELSE NULL END,
+	updated_at = NOW()
+WHERE id = $1;
+
+-- name: SetProfile :exec
+UPDATE users
+SET display_name = COALESCE(sqlc.narg('display_name'), display_name),
+	bio = COALESCE(sqlc.narg('bio'), bio),
+	location = COALESCE(sqlc.narg('location'), location),
+	website = COALESCE(sqlc.narg('website'), website),
+	updated_at = NOW()
+WHERE id = sqlc.arg('id');
+
+-- name: SetAvatarKey :exec
+UPDATE users
+SET avatar_key = $2,
+	updated_at = NOW()
+WHERE id = $1;
+
+-- name: SoftDeleteUser :exec
+UPDATE users
+SET deleted_at = NOW(),
+	updated_at = NOW()
+WHERE id = $1;
+
+-- name: CancelUserDeletion :exec
+UPDATE users
+SET deleted_at = NULL,
+	updated_at = NOW()
+WHERE id = $1;
+
+-- name: PurgeDeletedUsers :many
+DELETE FROM users
+WHERE deleted_at < $1
+RETURNING avatar_key;
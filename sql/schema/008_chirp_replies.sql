@@ -0,0 +1,8 @@
+-- +goose Up
+ALTER TABLE chirps
+ADD COLUMN reply_to UUID REFERENCES chirps(id) ON DELETE SET NULL;
+CREATE INDEX chirps_reply_to_idx ON chirps (reply_to, created_at, id);
+
+-- +goose Down
+ALTER TABLE chirps
+DROP COLUMN reply_to;
@@ -0,0 +1,23 @@
+-- +goose Up
+CREATE TABLE moderation_terms (
+	id UUID NOT NULL PRIMARY KEY,
+	term TEXT NOT NULL UNIQUE,
+	action TEXT NOT NULL CHECK(action IN ('mask', 'reject', 'flag')),
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL
+);
+INSERT INTO moderation_terms (id, term, action, created_at, updated_at)
+VALUES
+	(gen_random_uuid(), 'kerfuffle', 'mask', NOW(), NOW()),
+	(gen_random_uuid(), 'sharbert', 'mask', NOW(), NOW()),
+	(gen_random_uuid(), 'fornax', 'mask', NOW(), NOW());
+CREATE TABLE moderation_flags (
+	chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+	term TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	PRIMARY KEY(chirp_id, term)
+);
+
+-- +goose Down
+DROP TABLE moderation_flags;
+DROP TABLE moderation_terms;
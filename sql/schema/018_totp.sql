@@ -0,0 +1,22 @@
+-- +goose Up
+CREATE TABLE user_totp (
+	user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+	secret TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	-- NULL until the user confirms enrollment with a first code
+	enabled_at TIMESTAMP,
+	-- time step of the last accepted code, so codes can't be replayed
+	last_used_counter BIGINT
+);
+CREATE TABLE totp_recovery_codes (
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	code_hash TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	used_at TIMESTAMP,
+	PRIMARY KEY(user_id, code_hash)
+);
+
+-- +goose Down
+DROP TABLE totp_recovery_codes;
+DROP TABLE user_totp;
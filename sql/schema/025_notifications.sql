@@ -0,0 +1,18 @@
+-- +goose Up
+CREATE TABLE notifications (
+	id UUID PRIMARY KEY,
+	user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	actor_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	type TEXT NOT NULL CHECK (type IN ('reply', 'mention', 'like', 'rechirp', 'quote')),
+	-- the reply, mention or quote itself; for likes and rechirps the chirp that was liked or rechirped
+	chirp_id UUID NOT NULL REFERENCES chirps(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL,
+	read_at TIMESTAMP
+);
+CREATE INDEX notifications_user_id_idx ON notifications (user_id, created_at DESC, id DESC);
+CREATE INDEX notifications_unread_idx ON notifications (user_id) WHERE read_at IS NULL;
+-- unliking and liking again must not notify twice
+CREATE UNIQUE INDEX notifications_dedup_idx ON notifications (user_id, actor_id, type, chirp_id);
+
+-- +goose Down
+DROP TABLE notifications;
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/Kurlgargyey/chirpy/internal/pubsub"
+	"github.com/google/uuid"
+)
+
+const (
+	topicChirps       = "chirps"
+	eventChirpCreated = "chirp_created"
+	eventChirpDeleted = "chirp_deleted"
+
+	// events kept in memory for clients resuming with Last-Event-ID
+	eventHistorySize = 1000
+	heartbeatPeriod  = 15 * time.Second
+	// how long EventSource clients wait before reconnecting
+	streamRetry = 3 * time.Second
+)
+
+type deletedChirpResponse struct {
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+}
+
+// publishChirpCreated streams a newly stored chirp. The chirp is already saved, so failing
+// to publish it is only logged; clients catch up from GET /api/chirps.
+func (cfg *apiConfig) publishChirpCreated(ctx context.Context, chirp database.Chirp) {
+	response, err := cfg.chirpResponse(ctx, uuid.NullUUID{}, chirp)
+	if err != nil {
+		fmt.Println("error publishing chirp:", err)
+		return
+	}
+	dat, _ := json.Marshal(response)
+	cfg.events.Publish(pubsub.Event{
+		Topic:  topicChirps,
+		Type:   eventChirpCreated,
+		UserID: chirp.UserID,
+		Data:   dat,
+	})
+}
+
+func (cfg *apiConfig) publishChirpDeleted(chirp database.Chirp) {
+	dat, _ := json.Marshal(deletedChirpResponse{ID: chirp.ID, UserID: chirp.UserID})
+	cfg.events.Publish(pubsub.Event{
+		Topic:  topicChirps,
+		Type:   eventChirpDeleted,
+		UserID: chirp.UserID,
+		Data:   dat,
+	})
+}
+
+func writeSSEEvent(w http.ResponseWriter, event pubsub.Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data)
+}
+
+// streamChirpsHandler sends chirp_created and chirp_deleted events as Server-Sent Events.
+// A client reconnecting with Last-Event-ID first receives the events it missed; if some of
+// them are no longer retained it gets a reset event and should reload GET /api/chirps.
+// Chirps by users the viewer blocked or muted are left out.
+func (cfg *apiConfig) streamChirpsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		var authorID uuid.NullUUID
+		if authorStr := r.URL.Query().Get("author_id"); authorStr != "" {
+			parsed, err := uuid.Parse(authorStr)
+			if err != nil {
+				writeError(w, "invalid author_id", 400)
+				return
+			}
+			authorID = uuid.NullUUID{UUID: parsed, Valid: true}
+		}
+		// an unparseable Last-Event-ID is treated as a fresh connection
+		lastEventID, _ := strconv.ParseUint(r.Header.Get("Last-Event-ID"), 10, 64)
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeError(w, "streaming is not supported", 500)
+			return
+		}
+
+		viewerID := cfg.viewerID(r)
+		sub, replay, complete := cfg.events.Subscribe(lastEventID, func(event pubsub.Event) bool {
+			if isRelationshipChange(event, viewerID) {
+				return true
+			}
+			return event.Topic == topicChirps && (!authorID.Valid || event.UserID == authorID.UUID)
+		})
+		defer sub.Close()
+		// loaded after subscribing so that no change in between is missed
+		hidden, err := cfg.hiddenAuthors(r.Context(), viewerID)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving blocked and muted users: %s", err), 500)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		// stop reverse proxies such as nginx from buffering the stream
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(200)
+		fmt.Fprintf(w, "retry: %d\n\n", streamRetry.Milliseconds())
+		if !complete {
+			fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+		}
+		for _, event := range replay {
+			// hidden is already current, so relationship changes in the replay need no reload
+			if event.Topic == topicChirps && !hidden[event.UserID] {
+				writeSSEEvent(w, event)
+			}
+		}
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(heartbeatPeriod)
+		defer heartbeat.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+				flusher.Flush()
+			case event, ok := <-sub.Events():
+				if !ok {
+					// dropped for falling behind; the client reconnects with Last-Event-ID
+					return
+				}
+				if isRelationshipChange(event, viewerID) {
+					hidden, err = cfg.hiddenAuthors(r.Context(), viewerID)
+					if err != nil {
+						return
+					}
+					continue
+				}
+				if hidden[event.UserID] {
+					continue
+				}
+				writeSSEEvent(w, event)
+				flusher.Flush()
+			}
+		}
+	})
+}
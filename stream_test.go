@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/Kurlgargyey/chirpy/internal/auth"
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/Kurlgargyey/chirpy/internal/dbtest"
+	"github.com/Kurlgargyey/chirpy/internal/pubsub"
+)
+
+func TestRechirpIsStreamed(t *testing.T) {
+	db := dbtest.Open(t)
+	cfg := &apiConfig{
+		db:      database.New(db),
+		conn:    db,
+		jwtKeys: auth.NewHMACKeyRing("test secret"),
+		events:  pubsub.NewBroker(eventHistorySize),
+	}
+	ctx := context.Background()
+
+	author, err := cfg.db.CreateUser(ctx, database.CreateUserParams{Email: "author@example.com", HashedPassword: "unused"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %s", err)
+	}
+	rechirper, err := cfg.db.CreateUser(ctx, database.CreateUserParams{Email: "rechirper@example.com", HashedPassword: "unused"})
+	if err != nil {
+		t.Fatalf("CreateUser failed: %s", err)
+	}
+	chirp, err := cfg.db.CreateChirp(ctx, database.CreateChirpParams{Body: "worth repeating", UserID: author.ID})
+	if err != nil {
+		t.Fatalf("CreateChirp failed: %s", err)
+	}
+	token, err := auth.MakeJWT(rechirper.ID, cfg.jwtKeys)
+	if err != nil {
+		t.Fatalf("MakeJWT failed: %s", err)
+	}
+	sub, _, _ := cfg.events.Subscribe(0, func(event pubsub.Event) bool { return event.Topic == topicChirps })
+	defer sub.Close()
+
+	request := func(method string, handler http.Handler) int {
+		req := httptest.NewRequest(method, "/api/chirps/"+chirp.ID.String()+"/rechirps", nil)
+		req.SetPathValue("chirpID", chirp.ID.String())
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	nextEvent := func() pubsub.Event {
+		select {
+		case event := <-sub.Events():
+			return event
+		default:
+			t.Fatal("no event published")
+			return pubsub.Event{}
+		}
+	}
+
+	if code := request(http.MethodPost, cfg.rechirpHandler()); code != 201 {
+		t.Fatalf("rechirp: got status %d, want 201", code)
+	}
+	created := nextEvent()
+	var response chirpResponse
+	if err := json.Unmarshal(created.Data, &response); err != nil {
+		t.Fatalf("error decoding event data: %s", err)
+	}
+	if created.Type != eventChirpCreated || created.UserID != rechirper.ID || response.RechirpedChirp == nil {
+		t.Fatalf("rechirp: got %s event by %s with data %s", created.Type, created.UserID, created.Data)
+	}
+
+	if code := request(http.MethodDelete, cfg.undoRechirpHandler()); code != 204 {
+		t.Fatalf("undo rechirp: got status %d, want 204", code)
+	}
+	deleted := nextEvent()
+	var deletedResponse deletedChirpResponse
+	if err := json.Unmarshal(deleted.Data, &deletedResponse); err != nil {
+		t.Fatalf("error decoding event data: %s", err)
+	}
+	if deleted.Type != eventChirpDeleted || deletedResponse.ID != response.ID {
+		t.Fatalf("undo rechirp: got %s event with data %s, want deletion of %s", deleted.Type, deleted.Data, response.ID)
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Kurlgargyey/chirpy/internal/auth"
+	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/google/uuid"
+)
+
+const (
+	recoveryCodeCount = 10
+	// failed second-factor codes allowed per user, and per MFA token, before they are locked
+	maxMFAAttempts = 5
+	mfaLockout     = 15 * time.Minute
+)
+
+var (
+	errInvalidSecondFactor = errors.New("invalid two-factor code")
+	errTooManyMFAAttempts  = errors.New("too many failed two-factor attempts, try again later")
+)
+
+type totpCodeRequestBody struct {
+	Code string `json:"code"`
+}
+
+type totpEnrollmentResponse struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+type recoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type mfaLoginRequestBody struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+type mfaChallengeResponse struct {
+	MFARequired bool   `json:"mfa_required"`
+	MFAToken    string `json:"mfa_token"`
+}
+
+func (cfg *apiConfig) totpEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	totpState, err := cfg.db.GetTOTP(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return totpState.EnabledAt.Valid, nil
+}
+
+// verifySecondFactor accepts either a current TOTP code or one of the user's unused recovery codes.
+// Both are consumed: a TOTP code can't be replayed and a recovery code works once.
+func (cfg *apiConfig) verifySecondFactor(ctx context.Context, userID uuid.UUID, code string) (bool, error) {
+	totpState, err := cfg.db.GetTOTP(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+	if counter, ok := auth.ValidateTOTP(totpState.Secret, code, time.Now()); ok {
+		used, err := cfg.db.UseTOTPCounter(ctx, database.UseTOTPCounterParams{
+			UserID:          userID,
+			LastUsedCounter: sql.NullInt64{Int64: counter, Valid: true},
+		})
+		return used > 0, err
+	}
+	used, err := cfg.db.UseRecoveryCode(ctx, database.UseRecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(code)),
+	})
+	return used > 0, err
+}
+
+// checkSecondFactor verifies code and counts attempts against the user and the MFA token, if
+// one is given. A token that reaches maxMFAAttempts is useless for the rest of its lifetime, and
+// the user has to wait out mfaLockout before any code is checked again.
+func (cfg *apiConfig) checkSecondFactor(ctx context.Context, userID uuid.UUID, code, mfaToken string) error {
+	keys := []string{"user:" + userID.String()}
+	if mfaToken != "" {
+		keys = append(keys, "mfa_token:"+auth.HashToken(mfaToken))
+	}
+	if !cfg.mfaAttempts.Reserve(keys...) {
+		return errTooManyMFAAttempts
+	}
+	ok, err := cfg.verifySecondFactor(ctx, userID, code)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errInvalidSecondFactor
+	}
+	cfg.mfaAttempts.Reset(keys...)
+	return nil
+}
+
+// writeSecondFactorError responds to a failed checkSecondFactor, using invalidCode for a wrong code.
+func writeSecondFactorError(w http.ResponseWriter, err error, invalidCode int) {
+	switch {
+	case errors.Is(err, errTooManyMFAAttempts):
+		writeError(w, err.Error(), 429)
+	case errors.Is(err, errInvalidSecondFactor):
+		writeError(w, err.Error(), invalidCode)
+	default:
+		writeError(w, fmt.Sprintf("error verifying two-factor code: %s", err), 500)
+	}
+}
+
+func replaceRecoveryCodes(ctx context.Context, q *database.Queries, userID uuid.UUID) ([]string, error) {
+	codes, err := auth.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+	if err := q.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		err := q.CreateRecoveryCode(ctx, database.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: auth.HashToken(auth.NormalizeRecoveryCode(code)),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// enrollTOTPHandler starts (or restarts) enrollment. Two-factor stays off until the
+// user proves their authenticator works via confirmTOTPHandler.
+func (cfg *apiConfig) enrollTOTPHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error retrieving user: %s", err), 400)
+			return
+		}
+		secret, uri, err := auth.GenerateTOTPSecret(user.Email)
+		if err != nil {
+			writeError(w, fmt.Sprintf("error generating TOTP secret: %s", err), 500)
+			return
+		}
+		_, err = cfg.db.StartTOTPEnrollment(r.Context(), database.StartTOTPEnrollmentParams{
+			UserID: userID,
+			Secret: secret,
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			writeError(w, "two-factor authentication is already enabled", 409)
+			return
+		}
+		if err != nil {
+			writeError(w, fmt.Sprintf("error starting TOTP enrollment: %s", err), 400)
+			return
+		}
+		dat, _ := json.Marshal(totpEnrollmentResponse{Secret: secret, OtpauthURI: uri})
+		w.WriteHeader(200)
+		w.Write(dat)
+	})
+}
+
+func (cfg *apiConfig) confirmTOTPHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		var requestBody totpCodeRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			writeError(w, fmt.Sprintf("error decoding json: %s", err), 400)
+			return
+		}
+		totpState, err := cfg.db.GetTOTP(r.Context(), userID)
+		if err != nil {
+			writeError(w, "no two-factor enrollment in progress", 404)
+			return
+		}
+		if totpState.EnabledAt.Valid {
+			writeError(w, "two-factor authentication is already enabled", 409)
+			return
+		}
+		counter, ok := auth.ValidateTOTP(totpState.Secret, requestBody.Code, time.Now())
+		if !ok {
+			writeError(w, "invalid two-factor code", 400)
+			return
+		}
+		var codes []string
+		err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+			err := q.EnableTOTP(r.Context(), database.EnableTOTPParams{
+				UserID:          userID,
+				LastUsedCounter: sql.NullInt64{Int64: counter, Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+			codes, err = replaceRecoveryCodes(r.Context(), q, userID)
+			return err
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error enabling two-factor authentication: %s", err), 400)
+			return
+		}
+		dat, _ := json.Marshal(recoveryCodesResponse{RecoveryCodes: codes})
+		w.WriteHeader(200)
+		w.Write(dat)
+	})
+}
+
+func (cfg *apiConfig) disableTOTPHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		var requestBody totpCodeRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			writeError(w, fmt.Sprintf("error decoding json: %s", err), 400)
+			return
+		}
+		if enabled, err := cfg.totpEnabled(r.Context(), userID); err != nil || !enabled {
+			writeError(w, "two-factor authentication is not enabled", 404)
+			return
+		}
+		if err := cfg.checkSecondFactor(r.Context(), userID, requestBody.Code, ""); err != nil {
+			writeSecondFactorError(w, err, 403)
+			return
+		}
+		err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+			if err := q.DeleteRecoveryCodes(r.Context(), userID); err != nil {
+				return err
+			}
+			return q.DeleteTOTP(r.Context(), userID)
+		})
+		if err != nil {
+			writeError(w, fmt.Sprintf("error disabling two-factor authentication: %s", err), 400)
+			return
+		}
+		w.WriteHeader(204)
+	})
+}
+
+// mfaLoginHandler is the second step of a login for accounts with two-factor enabled.
+func (cfg *apiConfig) mfaLoginHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		var requestBody mfaLoginRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			writeError(w, fmt.Sprintf("error decoding json: %s", err), 400)
+			return
+		}
+		userID, err := auth.ValidateMFAToken(requestBody.MFAToken, cfg.jwtKeys)
+		if err != nil {
+			writeError(w, "invalid or expired MFA token", 401)
+			return
+		}
+		if err := cfg.checkSecondFactor(r.Context(), userID, requestBody.Code, requestBody.MFAToken); err != nil {
+			writeSecondFactorError(w, err, 401)
+			return
+		}
+		user, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			writeError(w, "invalid or expired MFA token", 401)
+			return
+		}
+		cfg.writeLoginResponse(w, r, user)
+	})
+}
@@ -1,22 +1,51 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"time"
 
 	"github.com/Kurlgargyey/chirpy/internal/auth"
+	"github.com/Kurlgargyey/chirpy/internal/chirptext"
 	"github.com/Kurlgargyey/chirpy/internal/database"
+	"github.com/Kurlgargyey/chirpy/internal/validation"
 	"github.com/google/uuid"
 )
 
 type userRequestBody struct {
-	Password string `json:"password"`
-	Email    string `json:"email"`
+	Password string  `json:"password"`
+	Email    string  `json:"email"`
+	Username *string `json:"username"`
 }
+
+// patchUserRequestBody only changes the fields that are present.
+// Changing email or password also requires the current password.
+type patchUserRequestBody struct {
+	Email           *string `json:"email"`
+	Password        *string `json:"password"`
+	Username        *string `json:"username"`
+	DisplayName     *string `json:"display_name"`
+	Bio             *string `json:"bio"`
+	Location        *string `json:"location"`
+	Website         *string `json:"website"`
+	CurrentPassword string  `json:"current_password"`
+}
+
+type patchUserResponse struct {
+	userResponse
+	// only set when the password changed and every earlier session was revoked
+	Token        string `json:"token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+}
+
 type loginRequestBody struct {
 	userRequestBody
+	// TOTP or recovery code; may also be supplied later via /api/login/mfa
+	Code string `json:"code"`
 }
 
 type webhookRequestBody struct {
@@ -29,11 +58,14 @@ type userIdJSON struct {
 }
 
 type userResponse struct {
-	ID          uuid.UUID `json:"id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	Email       string    `json:"email"`
-	IsChirpyRed bool      `json:"is_chirpy_red"`
+	ID            uuid.UUID `json:"id"`
+	CreatedAt     time.Time `json:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at"`
+	Email         string    `json:"email"`
+	IsChirpyRed   bool      `json:"is_chirpy_red"`
+	Username      *string   `json:"username"`
+	EmailVerified bool      `json:"email_verified"`
+	profileResponse
 }
 type loginResponse struct {
 	userResponse
@@ -42,7 +74,72 @@ type loginResponse struct {
 }
 
 type accessTokenResponse struct {
-	Token string `json:"token"`
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+const refreshTokenLifetime = time.Hour * 24 * 60
+
+var errRefreshTokenReused = errors.New("refresh token reused")
+
+func (cfg *apiConfig) newUserResponse(user database.User) userResponse {
+	response := userResponse{
+		ID:              user.ID,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+		Email:           user.Email,
+		IsChirpyRed:     user.IsChirpyRed,
+		EmailVerified:   user.EmailVerifiedAt.Valid,
+		profileResponse: cfg.profileResponse(user),
+	}
+	if user.Username.Valid {
+		response.Username = &user.Username.String
+	}
+	return response
+}
+
+var errAccountDeleted = errors.New("account has been deleted")
+
+// authenticatedUserID returns the user identified by the request's bearer access token.
+func (cfg *apiConfig) authenticatedUserID(r *http.Request) (uuid.UUID, error) {
+	bearerToken, err := auth.GetBearerToken(r.Header)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return cfg.activeUserID(r.Context(), bearerToken)
+}
+
+// activeUserID validates an access token and checks that its account has not been deleted.
+// Tokens issued before a deletion request stay validly signed until they expire.
+func (cfg *apiConfig) activeUserID(ctx context.Context, token string) (uuid.UUID, error) {
+	userID, _, err := cfg.activeToken(ctx, token)
+	return userID, err
+}
+
+// activeToken is activeUserID that also returns when the token expires.
+func (cfg *apiConfig) activeToken(ctx context.Context, token string) (uuid.UUID, time.Time, error) {
+	userID, expiresAt, err := auth.ValidateJWTExpiry(token, cfg.jwtKeys)
+	if err != nil {
+		return uuid.Nil, time.Time{}, err
+	}
+	user, err := cfg.db.GetUserByID(ctx, userID)
+	if err != nil {
+		return uuid.Nil, time.Time{}, err
+	}
+	if user.DeletedAt.Valid {
+		return uuid.Nil, time.Time{}, errAccountDeleted
+	}
+	return userID, expiresAt, nil
+}
+
+// viewerID is the optional counterpart of authenticatedUserID for endpoints that are public
+// but personalise their response when a valid access token is present.
+func (cfg *apiConfig) viewerID(r *http.Request) uuid.NullUUID {
+	userID, err := cfg.authenticatedUserID(r)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: userID, Valid: true}
 }
 
 func (cfg *apiConfig) createUserHandler() http.Handler {
@@ -55,13 +152,18 @@ func (cfg *apiConfig) createUserHandler() http.Handler {
 				writeError(w, fmt.Sprintf("error decoding json: %s", err), 400)
 				return
 			}
+			email, errs := validation.Email(requestBody.Email)
+			if len(errs) > 0 {
+				writeValidationErrors(w, errs)
+				return
+			}
 			hashed_pwd, err := auth.HashPassword(requestBody.Password)
 			if err != nil {
 				writeError(w, fmt.Sprintf("error hashing password: %s", err), 400)
 				return
 			}
 			userParams := database.CreateUserParams{
-				Email:          requestBody.Email,
+				Email:          email,
 				HashedPassword: hashed_pwd,
 			}
 			user, err := cfg.db.CreateUser(r.Context(), userParams)
@@ -69,13 +171,10 @@ func (cfg *apiConfig) createUserHandler() http.Handler {
 				writeError(w, fmt.Sprintf("error creating user: %s", err), 400)
 				return
 			}
-			response := userResponse{
-				ID:          user.ID,
-				CreatedAt:   user.CreatedAt,
-				UpdatedAt:   user.UpdatedAt,
-				Email:       user.Email,
-				IsChirpyRed: user.IsChirpyRed,
+			if err := cfg.sendVerificationEmail(r.Context(), user); err != nil {
+				fmt.Println("error sending verification email:", err)
 			}
+			response := cfg.newUserResponse(user)
 			dat, _ := json.Marshal(response)
 			w.WriteHeader(201)
 			w.Write(dat)
@@ -84,6 +183,7 @@ func (cfg *apiConfig) createUserHandler() http.Handler {
 
 func (cfg *apiConfig) loginHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
 		defer r.Body.Close()
 		var requestBody loginRequestBody
 		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
@@ -94,44 +194,68 @@ func (cfg *apiConfig) loginHandler() http.Handler {
 		hashErr := auth.CheckPasswordHash(requestBody.Password, user.HashedPassword)
 
 		if fetchErr != nil || hashErr != nil {
-			w.WriteHeader(401)
-			w.Write([]byte("incorrect email or password"))
+			writeError(w, "incorrect email or password", 401)
 			return
 		}
 
-		token, err := auth.MakeJWT(user.ID, cfg.jwtSecret)
-		if err != nil {
-			writeError(w, fmt.Sprintf("error obtaining JWT: %s", err), 400)
-			return
-		}
-		refreshToken, err := auth.MakeRefreshToken()
+		totpEnabled, err := cfg.totpEnabled(r.Context(), user.ID)
 		if err != nil {
-			writeError(w, fmt.Sprintf("error obtaining refresh token: %s", err), 400)
+			writeError(w, fmt.Sprintf("error checking two-factor authentication: %s", err), 400)
 			return
 		}
-		refreshTokenParams := database.CreateRefreshTokenParams{
-			Token:     refreshToken,
-			UserID:    user.ID,
-			ExpiresAt: time.Now().Add(time.Hour * 24 * 60),
-		}
-		_, refresh_err := cfg.db.CreateRefreshToken(r.Context(), refreshTokenParams)
-		if refresh_err != nil {
-			writeError(w, fmt.Sprintf("error committing refresh token to database: %s", refresh_err), 400)
-			return
+		if totpEnabled {
+			if requestBody.Code == "" {
+				mfaToken, err := auth.MakeMFAToken(user.ID, cfg.jwtKeys)
+				if err != nil {
+					writeError(w, fmt.Sprintf("error obtaining MFA token: %s", err), 400)
+					return
+				}
+				dat, _ := json.Marshal(mfaChallengeResponse{MFARequired: true, MFAToken: mfaToken})
+				w.Write(dat)
+				return
+			}
+			if err := cfg.checkSecondFactor(r.Context(), user.ID, requestBody.Code, ""); err != nil {
+				writeSecondFactorError(w, err, 401)
+				return
+			}
 		}
+		cfg.writeLoginResponse(w, r, user)
+	})
+}
 
-		response := loginResponse{
-			userResponse: userResponse{ID: user.ID,
-				CreatedAt:   user.CreatedAt,
-				UpdatedAt:   user.UpdatedAt,
-				Email:       user.Email,
-				IsChirpyRed: user.IsChirpyRed},
-			Token:        token,
-			RefreshToken: refreshToken,
+// writeLoginResponse starts a new session for a fully authenticated user.
+// Logging in during the deletion grace period restores the account.
+func (cfg *apiConfig) writeLoginResponse(w http.ResponseWriter, r *http.Request, user database.User) {
+	if user.DeletedAt.Valid {
+		if err := cfg.db.CancelUserDeletion(r.Context(), user.ID); err != nil {
+			writeError(w, fmt.Sprintf("error restoring account: %s", err), 400)
+			return
 		}
-		dat, _ := json.Marshal(response)
-		w.Write(dat)
+		user.DeletedAt = sql.NullTime{}
+	}
+	token, err := auth.MakeJWT(user.ID, cfg.jwtKeys)
+	if err != nil {
+		writeError(w, fmt.Sprintf("error obtaining JWT: %s", err), 400)
+		return
+	}
+	var refreshToken string
+	err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+		var err error
+		refreshToken, err = startSession(r, q, user.ID)
+		return err
 	})
+	if err != nil {
+		writeError(w, fmt.Sprintf("error obtaining refresh token: %s", err), 400)
+		return
+	}
+
+	response := loginResponse{
+		userResponse: cfg.newUserResponse(user),
+		Token:        token,
+		RefreshToken: refreshToken,
+	}
+	dat, _ := json.Marshal(response)
+	w.Write(dat)
 }
 
 func (cfg *apiConfig) refreshTokenHandler() http.Handler {
@@ -143,20 +267,80 @@ func (cfg *apiConfig) refreshTokenHandler() http.Handler {
 			return
 		}
 		token, err := cfg.db.GetRefreshToken(r.Context(), bearerToken)
-		if err != nil || token.RevokedAt.Valid {
+		if err != nil {
 			writeError(w, "could not obtain a valid refresh token", 401)
 			return
 		}
-		accessToken, err := auth.MakeJWT(token.UserID, cfg.jwtSecret)
+		if token.ReplacedBy.Valid {
+			cfg.revokeReusedRefreshToken(w, r, token)
+			return
+		}
+		if token.RevokedAt.Valid || !time.Now().UTC().Before(token.ExpiresAt) {
+			writeError(w, "could not obtain a valid refresh token", 401)
+			return
+		}
+		accessToken, err := auth.MakeJWT(token.UserID, cfg.jwtKeys)
 		if err != nil {
 			writeError(w, "could not obtain a new access token", 401)
 			return
 		}
-		dat, _ := json.Marshal(accessTokenResponse{Token: accessToken})
+		var refreshToken string
+		err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+			var err error
+			refreshToken, err = issueRefreshToken(r.Context(), q, token.UserID, token.FamilyID)
+			if err != nil {
+				return err
+			}
+			rotated, err := q.RotateRefreshToken(r.Context(), database.RotateRefreshTokenParams{
+				Token:      token.Token,
+				ReplacedBy: sql.NullString{String: refreshToken, Valid: true},
+			})
+			if err != nil {
+				return err
+			}
+			if rotated == 0 {
+				return errRefreshTokenReused
+			}
+			return q.TouchSession(r.Context(), token.FamilyID)
+		})
+		if errors.Is(err, errRefreshTokenReused) {
+			cfg.revokeReusedRefreshToken(w, r, token)
+			return
+		}
+		if err != nil {
+			writeError(w, fmt.Sprintf("error rotating refresh token: %s", err), 400)
+			return
+		}
+		dat, _ := json.Marshal(accessTokenResponse{Token: accessToken, RefreshToken: refreshToken})
 		w.Write(dat)
 	})
 }
 
+// issueRefreshToken stores a new refresh token in the given family. Login starts a new family
+// (see startSession); every refresh continues the family of the token it replaces.
+func issueRefreshToken(ctx context.Context, q *database.Queries, userID, familyID uuid.UUID) (string, error) {
+	refreshToken, err := auth.MakeRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	return q.CreateRefreshToken(ctx, database.CreateRefreshTokenParams{
+		Token:     refreshToken,
+		UserID:    userID,
+		ExpiresAt: time.Now().UTC().Add(refreshTokenLifetime),
+		FamilyID:  familyID,
+	})
+}
+
+// revokeReusedRefreshToken handles a refresh token presented after it was already rotated.
+// Either the client or an attacker holds a stolen copy, so the whole family is revoked.
+func (cfg *apiConfig) revokeReusedRefreshToken(w http.ResponseWriter, r *http.Request, token database.RefreshToken) {
+	if err := cfg.db.RevokeRefreshTokenFamily(r.Context(), token.FamilyID); err != nil {
+		writeError(w, fmt.Sprintf("error revoking refresh tokens: %s", err), 500)
+		return
+	}
+	writeError(w, "refresh token has already been used; please log in again", 401)
+}
+
 func (cfg *apiConfig) revokeTokenHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
@@ -174,6 +358,8 @@ func (cfg *apiConfig) revokeTokenHandler() http.Handler {
 	})
 }
 
+// updateUserHandler is the original PUT /api/users, which replaces email and password together.
+// New clients should use patchUserHandler.
 func (cfg *apiConfig) updateUserHandler() http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer r.Body.Close()
@@ -182,7 +368,7 @@ func (cfg *apiConfig) updateUserHandler() http.Handler {
 			writeError(w, "could not obtain a bearer token", 401)
 			return
 		}
-		userID, validationErr := auth.ValidateJWT(bearerToken, cfg.jwtSecret)
+		userID, validationErr := cfg.activeUserID(r.Context(), bearerToken)
 		if validationErr != nil {
 			w.WriteHeader(401)
 			return
@@ -192,29 +378,57 @@ func (cfg *apiConfig) updateUserHandler() http.Handler {
 			writeError(w, fmt.Sprintf("error decoding json: %s", err), 400)
 			return
 		}
+		email, errs := validation.Email(requestBody.Email)
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
 		hashed_pwd, err := auth.HashPassword(requestBody.Password)
 		if err != nil {
 			writeError(w, fmt.Sprintf("error hashing password: %s", err), 400)
 			return
 		}
+		if requestBody.Username != nil && !chirptext.ValidUsername(*requestBody.Username) {
+			writeError(w, "username must be 3-30 letters, digits or underscores", 400)
+			return
+		}
+		oldUser, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			writeError(w, "error updating user", 400)
+			return
+		}
 		updateParams := database.UpdateUserParams{
 			ID:             userID,
-			Email:          requestBody.Email,
+			Email:          email,
 			HashedPassword: hashed_pwd,
 		}
-		newUser, err := cfg.db.UpdateUser(r.Context(), updateParams)
+		var newUser database.User
+		err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+			var err error
+			newUser, err = q.UpdateUser(r.Context(), updateParams)
+			if err != nil || requestBody.Username == nil {
+				return err
+			}
+			newUser, err = q.SetUsername(r.Context(), database.SetUsernameParams{
+				ID:       userID,
+				Username: sql.NullString{String: *requestBody.Username, Valid: true},
+			})
+			return err
+		})
+		if isUniqueViolation(err, "users_username_idx") {
+			writeError(w, "username is already taken", 409)
+			return
+		}
 		if err != nil {
 			writeError(w, "error updating user", 400)
 			return
 		}
-		userResponse := userResponse{
-			ID:          newUser.ID,
-			CreatedAt:   newUser.CreatedAt,
-			UpdatedAt:   newUser.UpdatedAt,
-			Email:       newUser.Email,
-			IsChirpyRed: newUser.IsChirpyRed,
+		if newUser.Email != oldUser.Email {
+			if err := cfg.sendVerificationEmail(r.Context(), newUser); err != nil {
+				fmt.Println("error sending verification email:", err)
+			}
 		}
-		dat, _ := json.Marshal(userResponse)
+		dat, _ := json.Marshal(cfg.newUserResponse(newUser))
 		w.Write(dat)
 	})
 }
@@ -247,3 +461,157 @@ func (cfg *apiConfig) upgradeUserHandler() http.Handler {
 		w.WriteHeader(204)
 	})
 }
+
+func (cfg *apiConfig) patchUserHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Content-Type", "application/json")
+		defer r.Body.Close()
+		userID, err := cfg.authenticatedUserID(r)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+		var requestBody patchUserRequestBody
+		if err := json.NewDecoder(r.Body).Decode(&requestBody); err != nil {
+			writeError(w, fmt.Sprintf("error decoding json: %s", err), 400)
+			return
+		}
+		oldUser, err := cfg.db.GetUserByID(r.Context(), userID)
+		if err != nil {
+			w.WriteHeader(401)
+			return
+		}
+
+		var errs validation.Errors
+		var email string
+		if requestBody.Email != nil {
+			var emailErrs validation.Errors
+			email, emailErrs = validation.Email(*requestBody.Email)
+			errs = append(errs, emailErrs...)
+		}
+		if requestBody.Password != nil && *requestBody.Password == "" {
+			errs.Add(validation.CodeEmpty, "password", "password must not be empty")
+		}
+		if requestBody.Username != nil && !chirptext.ValidUsername(*requestBody.Username) {
+			errs.Add(validation.CodeInvalid, "username", "username must be 3-30 letters, digits or underscores")
+		}
+		profile, profileErrs := validateProfile(requestBody)
+		profile.ID = userID
+		errs = append(errs, profileErrs...)
+		sensitive := requestBody.Email != nil || requestBody.Password != nil
+		if sensitive && requestBody.CurrentPassword == "" {
+			errs.Add(validation.CodeRequired, "current_password", "current password is required to change email or password")
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+		if sensitive && auth.CheckPasswordHash(requestBody.CurrentPassword, oldUser.HashedPassword) != nil {
+			writeError(w, "current password is incorrect", 403)
+			return
+		}
+		var hashedPassword string
+		if requestBody.Password != nil {
+			hashedPassword, err = auth.HashPassword(*requestBody.Password)
+			if err != nil {
+				writeError(w, fmt.Sprintf("error hashing password: %s", err), 400)
+				return
+			}
+		}
+
+		var response patchUserResponse
+		var newUser database.User
+		err = cfg.withTx(r.Context(), func(q *database.Queries) error {
+			if requestBody.Email != nil {
+				err := q.SetEmail(r.Context(), database.SetEmailParams{ID: userID, Email: email})
+				if err != nil {
+					return err
+				}
+			}
+			if requestBody.Username != nil {
+				_, err := q.SetUsername(r.Context(), database.SetUsernameParams{
+					ID:       userID,
+					Username: sql.NullString{String: *requestBody.Username, Valid: true},
+				})
+				if err != nil {
+					return err
+				}
+			}
+			if profile.DisplayName.Valid || profile.Bio.Valid || profile.Location.Valid || profile.Website.Valid {
+				if err := q.SetProfile(r.Context(), profile); err != nil {
+					return err
+				}
+			}
+			if requestBody.Password != nil {
+				err := q.SetPassword(r.Context(), database.SetPasswordParams{ID: userID, HashedPassword: hashedPassword})
+				if err != nil {
+					return err
+				}
+				// log out every other device; the caller continues in a fresh session
+				if err := q.RevokeAllRefreshTokens(r.Context(), userID); err != nil {
+					return err
+				}
+				response.RefreshToken, err = startSession(r, q, userID)
+				if err != nil {
+					return err
+				}
+			}
+			var err error
+			newUser, err = q.GetUserByID(r.Context(), userID)
+			return err
+		})
+		if isUniqueViolation(err, "users_email_key") {
+			writeError(w, "email is already in use", 409)
+			return
+		}
+		if isUniqueViolation(err, "users_username_idx") {
+			writeError(w, "username is already taken", 409)
+			return
+		}
+		if err != nil {
+			writeError(w, fmt.Sprintf("error updating user: %s", err), 400)
+			return
+		}
+		if response.RefreshToken != "" {
+			response.Token, err = auth.MakeJWT(userID, cfg.jwtKeys)
+			if err != nil {
+				writeError(w, fmt.Sprintf("error obtaining JWT: %s", err), 400)
+				return
+			}
+		}
+		if newUser.Email != oldUser.Email {
+			if err := cfg.sendVerificationEmail(r.Context(), newUser); err != nil {
+				fmt.Println("error sending verification email:", err)
+			}
+		}
+		response.userResponse = cfg.newUserResponse(newUser)
+		dat, _ := json.Marshal(response)
+		w.WriteHeader(200)
+		w.Write(dat)
+	})
+}
+
+// validateProfile checks the profile fields present in a PATCH; absent fields stay NULL and are left unchanged.
+func validateProfile(requestBody patchUserRequestBody) (database.SetProfileParams, validation.Errors) {
+	var profile database.SetProfileParams
+	var errs validation.Errors
+	optional := func(value *string, check func(string) (string, validation.Errors)) sql.NullString {
+		if value == nil {
+			return sql.NullString{}
+		}
+		cleaned, fieldErrs := check(*value)
+		errs = append(errs, fieldErrs...)
+		return sql.NullString{String: cleaned, Valid: len(fieldErrs) == 0}
+	}
+	profile.DisplayName = optional(requestBody.DisplayName, func(value string) (string, validation.Errors) {
+		return validation.ProfileText("display_name", value, validation.MaxDisplayNameLength)
+	})
+	profile.Bio = optional(requestBody.Bio, func(value string) (string, validation.Errors) {
+		return validation.ProfileText("bio", value, validation.MaxBioLength)
+	})
+	profile.Location = optional(requestBody.Location, func(value string) (string, validation.Errors) {
+		return validation.ProfileText("location", value, validation.MaxLocationLength)
+	})
+	profile.Website = optional(requestBody.Website, validation.Website)
+	return profile, errs
+}
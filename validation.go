@@ -5,8 +5,8 @@ import (
 	"fmt"
 	"mime"
 	"net/http"
-	"regexp"
-	"strings"
+
+	"github.com/Kurlgargyey/chirpy/internal/validation"
 )
 
 type Chirp struct {
@@ -14,17 +14,17 @@ type Chirp struct {
 }
 
 type ValidationError struct {
-	Error string `json:"error"`
+	Error  string            `json:"error"`
+	Errors validation.Errors `json:"errors,omitempty"`
 }
 type CleanedChirp struct {
 	CleanedBody string `json:"cleaned_body"`
 }
 
-func validateChirpHandler() http.Handler {
+func (cfg *apiConfig) validateChirpHandler() http.Handler {
 	return http.HandlerFunc(
 		func(w http.ResponseWriter, r *http.Request) {
 			defer r.Body.Close()
-			maxChirpLength := 140
 			w.Header().Add("Content-Type", "application/json")
 			contentType := r.Header.Get("Content-Type")
 			mediaType, _, err := mime.ParseMediaType(contentType)
@@ -39,29 +39,30 @@ func validateChirpHandler() http.Handler {
 				writeError(w, fmt.Sprintf("%s", err), 400)
 				return
 			}
-			if chirp.Body == nil {
-				writeError(w, "missing required fields: body", 400)
-				return
-			}
-			*chirp.Body = strings.TrimSpace(*chirp.Body)
-			if len(*chirp.Body) > maxChirpLength {
-				writeError(w, "overlong chirp", 400)
-				return
-			}
-			if len(*chirp.Body) == 0 {
-				writeError(w, "empty chirp", 400)
+			cleanedBody, errs := cfg.validateChirpBody(chirp.Body)
+			if len(errs) > 0 {
+				writeValidationErrors(w, errs)
 				return
 			}
-			chirp.CleanBody()
-			dat, _ := json.Marshal(CleanedChirp{CleanedBody: *chirp.Body})
+			dat, _ := json.Marshal(CleanedChirp{CleanedBody: cleanedBody})
 			w.WriteHeader(200)
 			w.Write(dat)
 		})
 }
 
-func (chirp *Chirp) CleanBody() {
-	re := regexp.MustCompile(`(?i)kerfuffle|(?i)sharbert|(?i)fornax`)
-	*chirp.Body = re.ReplaceAllString(*chirp.Body, "****")
+// validateChirpBody applies the chirp rules shared by every route that accepts a chirp body
+// and returns the trimmed, moderated body.
+func (cfg *apiConfig) validateChirpBody(body *string) (string, validation.Errors) {
+	cleanedBody, errs := validation.ChirpBody(body)
+	if len(errs) > 0 {
+		return "", errs
+	}
+	moderatedBody, err := cfg.moderateChirpBody(cleanedBody)
+	if err != nil {
+		errs.Add(validation.CodeProhibitedContent, "body", err.Error())
+		return "", errs
+	}
+	return moderatedBody, nil
 }
 
 func writeError(w http.ResponseWriter, err string, code int) {
@@ -70,3 +71,11 @@ func writeError(w http.ResponseWriter, err string, code int) {
 	w.WriteHeader(code)
 	w.Write(dat)
 }
+
+// writeValidationErrors reports every field error, keeping the summary in "error" for older clients.
+func writeValidationErrors(w http.ResponseWriter, errs validation.Errors) {
+	response := ValidationError{Error: errs.Error(), Errors: errs}
+	dat, _ := json.Marshal(response)
+	w.WriteHeader(400)
+	w.Write(dat)
+}
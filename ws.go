@@ -0,0 +1,312 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Kurlgargyey/chirpy/internal/auth"
+	"github.com/Kurlgargyey/chirpy/internal/pubsub"
+	"github.com/coder/websocket"
+	"github.com/google/uuid"
+)
+
+const (
+	wsAuthTimeout  = 10 * time.Second
+	wsWriteTimeout = 10 * time.Second
+	wsPingPeriod   = 30 * time.Second
+	wsReadLimit    = 4096
+	wsMaxChannels  = 50
+)
+
+// Messages from the client:
+//
+//	{"type": "auth", "token": "<access token>"}    first message without an Authorization header
+//	{"type": "subscribe", "channel": "chirps"}     also "chirps:<user id>" and "notifications"
+//	{"type": "unsubscribe", "channel": "chirps"}
+//
+// The connection is closed when its access token expires. Sending another auth message with a
+// fresh token for the same user before then keeps it open.
+type wsClientMessage struct {
+	Type    string `json:"type"`
+	Token   string `json:"token,omitempty"`
+	Channel string `json:"channel,omitempty"`
+}
+
+// Messages from the server are "authenticated", "subscribed", "unsubscribed", "error" and
+// "event", which carries the ID, type and payload of a published event.
+type wsServerMessage struct {
+	Type      string          `json:"type"`
+	UserID    *uuid.UUID      `json:"user_id,omitempty"`
+	ExpiresAt *time.Time      `json:"expires_at,omitempty"`
+	Channel   string          `json:"channel,omitempty"`
+	ID        uint64          `json:"id,omitempty"`
+	Event     string          `json:"event,omitempty"`
+	Data      json.RawMessage `json:"data,omitempty"`
+	Message   string          `json:"message,omitempty"`
+}
+
+// wsChannel is a parsed channel name: all chirps, one user's chirps or the client's own notifications.
+type wsChannel struct {
+	topic  string
+	userID uuid.NullUUID
+}
+
+func parseWSChannel(name string, userID uuid.UUID) (wsChannel, error) {
+	switch {
+	case name == "chirps":
+		return wsChannel{topic: topicChirps}, nil
+	case strings.HasPrefix(name, "chirps:"):
+		authorID, err := uuid.Parse(strings.TrimPrefix(name, "chirps:"))
+		if err != nil {
+			return wsChannel{}, fmt.Errorf("invalid user id in channel %q", name)
+		}
+		return wsChannel{topic: topicChirps, userID: uuid.NullUUID{UUID: authorID, Valid: true}}, nil
+	case name == "notifications":
+		return wsChannel{topic: topicNotifications, userID: uuid.NullUUID{UUID: userID, Valid: true}}, nil
+	default:
+		return wsChannel{}, fmt.Errorf("unknown channel %q", name)
+	}
+}
+
+func (c wsChannel) matches(event pubsub.Event) bool {
+	return event.Topic == c.topic && (!c.userID.Valid || event.UserID == c.userID.UUID)
+}
+
+type wsClient struct {
+	cfg    *apiConfig
+	conn   *websocket.Conn
+	userID uuid.UUID
+	// expiry of each token the client re-authenticates with
+	renewed chan time.Time
+
+	mu       sync.Mutex
+	channels map[wsChannel]string // parsed channel -> name the client subscribed with
+}
+
+// channelFor is the broker filter for the client. It returns the name of a subscribed channel
+// the event belongs to, preferring the most specific one so each event is sent only once.
+func (c *wsClient) channelFor(event pubsub.Event) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var match string
+	found := false
+	for channel, name := range c.channels {
+		if !channel.matches(event) {
+			continue
+		}
+		if !found || channel.userID.Valid {
+			match = name
+			found = true
+		}
+	}
+	return match, found
+}
+
+func (c *wsClient) wants(event pubsub.Event) bool {
+	if isRelationshipChange(event, uuid.NullUUID{UUID: c.userID, Valid: true}) {
+		return true
+	}
+	_, ok := c.channelFor(event)
+	return ok
+}
+
+// send writes one message. A client that does not accept it within wsWriteTimeout is
+// disconnected, which keeps a stalled socket from holding on to its subscription.
+func (c *wsClient) send(ctx context.Context, msg wsServerMessage) error {
+	dat, _ := json.Marshal(msg)
+	ctx, cancel := context.WithTimeout(ctx, wsWriteTimeout)
+	defer cancel()
+	return c.conn.Write(ctx, websocket.MessageText, dat)
+}
+
+// readMessages handles subscribe and unsubscribe requests until the connection fails.
+func (c *wsClient) readMessages(ctx context.Context) {
+	for {
+		_, dat, err := c.conn.Read(ctx)
+		if err != nil {
+			return
+		}
+		var msg wsClientMessage
+		if err := json.Unmarshal(dat, &msg); err != nil {
+			c.send(ctx, wsServerMessage{Type: "error", Message: fmt.Sprintf("error decoding json: %s", err)})
+			continue
+		}
+		if err := c.handle(ctx, msg); err != nil {
+			c.send(ctx, wsServerMessage{Type: "error", Channel: msg.Channel, Message: err.Error()})
+		}
+	}
+}
+
+func (c *wsClient) handle(ctx context.Context, msg wsClientMessage) error {
+	if msg.Type == "auth" {
+		return c.reauthenticate(ctx, msg.Token)
+	}
+	if msg.Type != "subscribe" && msg.Type != "unsubscribe" {
+		return fmt.Errorf("unknown message type %q", msg.Type)
+	}
+	channel, err := parseWSChannel(msg.Channel, c.userID)
+	if err != nil {
+		return err
+	}
+	c.mu.Lock()
+	if msg.Type == "subscribe" {
+		if _, ok := c.channels[channel]; !ok && len(c.channels) >= wsMaxChannels {
+			c.mu.Unlock()
+			return fmt.Errorf("at most %d channels per connection", wsMaxChannels)
+		}
+		c.channels[channel] = msg.Channel
+	} else {
+		delete(c.channels, channel)
+	}
+	c.mu.Unlock()
+	return c.send(ctx, wsServerMessage{Type: msg.Type + "d", Channel: msg.Channel})
+}
+
+// reauthenticate extends the connection to the expiry of a fresh token for the same user.
+func (c *wsClient) reauthenticate(ctx context.Context, token string) error {
+	userID, expiresAt, err := c.cfg.activeToken(ctx, token)
+	if err != nil {
+		return errors.New("invalid access token")
+	}
+	if userID != c.userID {
+		return errors.New("the access token belongs to another user")
+	}
+	// only the newest expiry matters
+	select {
+	case <-c.renewed:
+	default:
+	}
+	c.renewed <- expiresAt
+	return c.send(ctx, wsServerMessage{Type: "authenticated", UserID: &userID, ExpiresAt: &expiresAt})
+}
+
+// authenticateWS reads the access token from the first message of a connection that was
+// opened without an Authorization header, as browsers cannot set one on a WebSocket.
+func (cfg *apiConfig) authenticateWS(ctx context.Context, conn *websocket.Conn) (uuid.UUID, time.Time, error) {
+	ctx, cancel := context.WithTimeout(ctx, wsAuthTimeout)
+	defer cancel()
+	_, dat, err := conn.Read(ctx)
+	if err != nil {
+		return uuid.Nil, time.Time{}, err
+	}
+	var msg wsClientMessage
+	if err := json.Unmarshal(dat, &msg); err != nil || msg.Type != "auth" {
+		return uuid.Nil, time.Time{}, errors.New("expected an auth message")
+	}
+	return cfg.activeToken(ctx, msg.Token)
+}
+
+// websocketHandler is the WebSocket gateway for chirp and notification events. Clients that
+// fall too far behind are disconnected rather than slowing down publishing; they reconnect and
+// catch up through the REST endpoints. Chirps by users the client blocked or muted are not sent.
+func (cfg *apiConfig) websocketHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var userID uuid.UUID
+		var expiresAt time.Time
+		authenticated := false
+		if bearerToken, err := auth.GetBearerToken(r.Header); err == nil {
+			userID, expiresAt, err = cfg.activeToken(r.Context(), bearerToken)
+			if err != nil {
+				w.WriteHeader(401)
+				return
+			}
+			authenticated = true
+		}
+		conn, err := websocket.Accept(w, r, nil)
+		if err != nil {
+			// Accept has already responded
+			return
+		}
+		defer conn.CloseNow()
+		conn.SetReadLimit(wsReadLimit)
+		ctx, cancel := context.WithCancel(r.Context())
+		defer cancel()
+		if !authenticated {
+			userID, expiresAt, err = cfg.authenticateWS(ctx, conn)
+			if err != nil {
+				conn.Close(websocket.StatusPolicyViolation, "authentication failed")
+				return
+			}
+		}
+
+		client := &wsClient{
+			cfg:      cfg,
+			conn:     conn,
+			userID:   userID,
+			renewed:  make(chan time.Time, 1),
+			channels: map[wsChannel]string{},
+		}
+		viewerID := uuid.NullUUID{UUID: userID, Valid: true}
+		sub, _, _ := cfg.events.Subscribe(0, client.wants)
+		defer sub.Close()
+		hidden, err := cfg.hiddenAuthors(ctx, viewerID)
+		if err != nil {
+			conn.Close(websocket.StatusInternalError, "error retrieving blocked and muted users")
+			return
+		}
+		if err := client.send(ctx, wsServerMessage{Type: "authenticated", UserID: &userID, ExpiresAt: &expiresAt}); err != nil {
+			return
+		}
+		go func() {
+			client.readMessages(ctx)
+			cancel()
+		}()
+
+		ping := time.NewTicker(wsPingPeriod)
+		defer ping.Stop()
+		expiry := time.NewTimer(time.Until(expiresAt))
+		defer expiry.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-expiry.C:
+				conn.Close(websocket.StatusPolicyViolation, "access token expired")
+				return
+			case expiresAt := <-client.renewed:
+				expiry.Reset(time.Until(expiresAt))
+			case <-ping.C:
+				pingCtx, pingCancel := context.WithTimeout(ctx, wsWriteTimeout)
+				err := conn.Ping(pingCtx)
+				pingCancel()
+				if err != nil {
+					return
+				}
+			case event, ok := <-sub.Events():
+				if !ok {
+					conn.Close(websocket.StatusTryAgainLater, "too slow to keep up with events")
+					return
+				}
+				if isRelationshipChange(event, viewerID) {
+					hidden, err = cfg.hiddenAuthors(ctx, viewerID)
+					if err != nil {
+						conn.Close(websocket.StatusInternalError, "error retrieving blocked and muted users")
+						return
+					}
+					continue
+				}
+				// the client may have unsubscribed since the event was queued
+				channel, subscribed := client.channelFor(event)
+				if !subscribed || (event.Topic == topicChirps && hidden[event.UserID]) {
+					continue
+				}
+				err := client.send(ctx, wsServerMessage{
+					Type:    "event",
+					Channel: channel,
+					ID:      event.ID,
+					Event:   event.Type,
+					Data:    event.Data,
+				})
+				if err != nil {
+					return
+				}
+			}
+		}
+	})
+}